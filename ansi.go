@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/rivo/tview"
+	"golang.org/x/term"
+)
+
+const (
+	colorsAlways = "always"
+	colorsNever  = "never"
+)
+
+// ansiPattern matches CSI sequences, OSC strings, charset designations and
+// two-byte escape sequences.
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[()*+][0-9A-Za-z]|\x1b[@-Z\\-_]`)
+
+// stripANSI removes terminal escape sequences from s.
+func stripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// escapeTviewText escapes text that tview would otherwise interpret as style
+// or region tags while leaving ANSI escape sequences intact for tview.ANSIWriter.
+func escapeTviewText(s string) string {
+	if !strings.Contains(s, "[") {
+		return s
+	}
+	locs := ansiPattern.FindAllStringIndex(s, -1)
+	if len(locs) == 0 {
+		return tview.Escape(s)
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range locs {
+		b.WriteString(tview.Escape(s[last:loc[0]]))
+		b.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(tview.Escape(s[last:]))
+	return b.String()
+}
+
+// showsColors is set when goncurrently itself shows colors, so that with
+// colors: auto child processes color their output as well.
+var showsColors bool
+
+// detectColors reports whether goncurrently shows colors: in the TUI or on a
+// terminal, unless disabled with noColors or the NO_COLOR variable.
+func detectColors(noColors, tui bool) bool {
+	if noColors || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return tui || term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// colorEnv returns the environment variables that make child processes
+// enable or disable colored output regardless of whether they write to a pipe.
+// With auto they are only forced when goncurrently shows colors itself.
+func colorEnv(mode string) []string {
+	switch mode {
+	case colorsAlways:
+		env := []string{"FORCE_COLOR=1", "CLICOLOR_FORCE=1", "CLICOLOR=1"}
+		if term := os.Getenv("TERM"); term == "" || term == "dumb" {
+			env = append(env, "TERM=xterm-256color")
+		}
+		return env
+	case colorsNever:
+		return []string{"NO_COLOR=1", "FORCE_COLOR=0", "CLICOLOR=0", "TERM=dumb"}
+	default:
+		if !showsColors {
+			return nil
+		}
+		return []string{"FORCE_COLOR=1", "CLICOLOR_FORCE=1"}
+	}
+}
+
+// stripOutput wraps the writers so that escape sequences are removed from every line.
+func stripOutput(enabled bool, stdoutWriter, stderrWriter func(string)) (func(string), func(string)) {
+	if !enabled {
+		return stdoutWriter, stderrWriter
+	}
+	wrap := func(writeLine func(string)) func(string) {
+		if writeLine == nil {
+			return nil
+		}
+		return func(line string) {
+			writeLine(stripANSI(line))
+		}
+	}
+	return wrap(stdoutWriter), wrap(stderrWriter)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "plain text", input: "hello", want: "hello"},
+		{name: "sgr colors", input: "\x1b[31;1merror\x1b[0m done", want: "error done"},
+		{name: "cursor movement", input: "\x1b[2K\x1b[1Gprogress 50%", want: "progress 50%"},
+		{name: "osc hyperlink", input: "\x1b]8;;https://example.com\x07link\x1b]8;;\x07", want: "link"},
+		{name: "charset selection", input: "\x1b(Bplain", want: "plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripANSI(tt.input); got != tt.want {
+				t.Errorf("stripANSI(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeTviewText(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "bracketed level", input: "[INFO] started", want: "[INFO] started"},
+		{name: "color tag lookalike", input: "[red]not red", want: "[red]not red"},
+		{name: "ansi colors preserved", input: "\x1b[32m[ok]\x1b[0m ready", want: "[ok] ready"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			view := tview.NewTextView().SetDynamicColors(true)
+			writer := tview.ANSIWriter(&buf)
+			if _, err := writer.Write([]byte(escapeTviewText(tt.input))); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			view.SetText(buf.String())
+			if got := strings.TrimSpace(view.GetText(true)); got != tt.want {
+				t.Errorf("rendered %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColorEnv(t *testing.T) {
+	t.Setenv("TERM", "dumb")
+	always := colorEnv(colorsAlways)
+	for _, want := range []string{"FORCE_COLOR=1", "CLICOLOR_FORCE=1", "TERM=xterm-256color"} {
+		if !slices.Contains(always, want) {
+			t.Errorf("expected %q in always env %v", want, always)
+		}
+	}
+
+	t.Setenv("TERM", "screen-256color")
+	if slices.Contains(colorEnv(colorsAlways), "TERM=xterm-256color") {
+		t.Error("always must keep a capable TERM untouched")
+	}
+
+	never := colorEnv(colorsNever)
+	for _, want := range []string{"NO_COLOR=1", "FORCE_COLOR=0", "TERM=dumb"} {
+		if !slices.Contains(never, want) {
+			t.Errorf("expected %q in never env %v", want, never)
+		}
+	}
+
+	if env := colorEnv("auto"); env != nil {
+		t.Errorf("expected no variables for auto without colors, got %v", env)
+	}
+	showsColors = true
+	defer func() { showsColors = false }()
+	auto := colorEnv("auto")
+	for _, want := range []string{"FORCE_COLOR=1", "CLICOLOR_FORCE=1"} {
+		if !slices.Contains(auto, want) {
+			t.Errorf("expected %q in auto env %v when colors are shown", want, auto)
+		}
+	}
+}
+
+func TestDetectColors(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	tests := []struct {
+		name     string
+		noColors bool
+		tui      bool
+		want     bool
+	}{
+		{"tui", false, true, true},
+		{"tui without colors", true, true, false},
+	}
+	for _, tt := range tests {
+		if got := detectColors(tt.noColors, tt.tui); got != tt.want {
+			t.Errorf("%s: detectColors() = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+	t.Setenv("NO_COLOR", "1")
+	if detectColors(false, true) {
+		t.Error("NO_COLOR must disable colors")
+	}
+}
+
+func TestCommandEnv(t *testing.T) {
+	if env := commandEnv(CommandConfig{}); env != nil {
+		t.Errorf("expected inherited environment, got %d entries", len(env))
+	}
+
+	env := commandEnv(CommandConfig{Colors: colorsNever, Env: map[string]string{"TERM": "vt100"}})
+	termIdx := slices.Index(env, "TERM=dumb")
+	userIdx := slices.Index(env, "TERM=vt100")
+	if termIdx < 0 || userIdx < termIdx {
+		t.Errorf("expected user env to override color env, got TERM entries at %d and %d", termIdx, userIdx)
+	}
+}
+
+func TestStripOutput(t *testing.T) {
+	var lines []string
+	writeFunc := func(line string) {
+		lines = append(lines, line)
+	}
+	stdoutWriter, _ := stripOutput(true, writeFunc, writeFunc)
+	stdoutWriter("\x1b[1mbold\x1b[0m")
+	if len(lines) != 1 || lines[0] != "bold" {
+		t.Errorf("unexpected stripped output: %v", lines)
+	}
+}
@@ -29,6 +29,9 @@ func defaultPanelStyles(commands []CommandConfig) map[string]panelAppearance {
 	styles := make(map[string]panelAppearance, len(commands)+1)
 	for i, c := range commands {
 		panelColor := panelColors[i%len(panelColors)]
+		if parsed, err := parseColor(c.Color); c.Color != "" && err == nil && parsed != tcell.ColorDefault {
+			panelColor = parsed
+		}
 		styles[c.Name] = panelAppearance{
 			BorderColor:     panelColor,
 			TitleColor:      panelColor,
@@ -3,16 +3,22 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/fatih/color"
 )
 
+// outputDrainTimeout bounds the time spent reading the output left in the
+// pipes of an exited command, which its own children may keep open.
+const outputDrainTimeout = time.Second
+
 func streamOutput(writeLine func(string), r io.Reader) {
 	if writeLine == nil {
 		_, _ = io.Copy(io.Discard, r) //nolint:errcheck
@@ -24,6 +30,45 @@ func streamOutput(writeLine func(string), r io.Reader) {
 	}
 }
 
+// instrumentOutput builds the per-line pipeline between a command's streams and
+// its router writers: escape sequences are stripped first when requested,
+// onOutput rules and readiness see the unfiltered lines, filters drop and
+// redact them, the record captures the redacted text and highlights are
+// applied last. A nil control channel disables onOutput rules.
+func instrumentOutput(c CommandConfig, rec *commandRecord, control chan<- controlRequest, requestStop func(), stdoutWriter, stderrWriter func(string)) (func(string), func(string)) {
+	filter := mustCompileFilters(c.Filters, c.Name)
+	stdoutWriter, stderrWriter = highlightOutput(filter, stdoutWriter, stderrWriter)
+	stdoutWriter, stderrWriter = captureOutput(rec, stdoutWriter, stderrWriter)
+	stdoutWriter, stderrWriter = filterOutput(filter, stdoutWriter, stderrWriter)
+	stdoutWriter, stderrWriter = watchReadiness(c, rec, stdoutWriter, stderrWriter)
+	if control != nil {
+		stdoutWriter, stderrWriter = watchOutputRules(c, filter, control, requestStop, stdoutWriter, stderrWriter)
+	}
+	return stripOutput(c.StripANSI, stdoutWriter, stderrWriter)
+}
+
+// watchReadiness wraps the output writers so that the first line matching the
+// command's readyPattern marks the record as ready.
+func watchReadiness(c CommandConfig, rec *commandRecord, stdoutWriter, stderrWriter func(string)) (func(string), func(string)) {
+	pattern := mustCompilePatternField("readyPattern", c.ReadyPattern, c.Name)
+	if pattern == nil || rec == nil {
+		return stdoutWriter, stderrWriter
+	}
+	rec.expectReadyPattern()
+	wrap := func(writeLine func(string)) func(string) {
+		if writeLine == nil {
+			return nil
+		}
+		return func(line string) {
+			if pattern.MatchString(line) {
+				rec.markReady()
+			}
+			writeLine(line)
+		}
+	}
+	return wrap(stdoutWriter), wrap(stderrWriter)
+}
+
 func logCommandLine(stdoutWriter, stderrWriter func(string), identifier, message string) {
 	switch {
 	case stderrWriter != nil:
@@ -60,67 +105,269 @@ func terminateProcess(cmd *exec.Cmd, killTimeout time.Duration, done <-chan erro
 	}
 }
 
-func startProcess(c CommandConfig) (cmd *exec.Cmd, ctx context.Context, cancel context.CancelFunc, stdout, stderr io.ReadCloser, err error) {
+// commandEnv builds the child environment from the parent's plus the color
+// settings and the command's own variables. It returns nil when the child can
+// simply inherit the parent environment.
+func commandEnv(c CommandConfig) []string {
+	extra := colorEnv(c.Colors)
+	if c.Env == nil && extra == nil {
+		return nil
+	}
+	env := append(os.Environ(), extra...)
+	for k, v := range c.Env {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	return env
+}
+
+// process is a started command together with the pipes connected to it.
+type process struct {
+	cmd    *exec.Cmd
+	ctx    context.Context
+	cancel context.CancelFunc
+	stdout io.ReadCloser
+	stderr io.ReadCloser
+	// stdin is only connected for commands with stdin enabled.
+	stdin io.WriteCloser
+	// pty is the master of the pseudo-terminal of commands run with pty,
+	// carrying their merged output.
+	pty      *os.File
+	ptySlave *os.File
+	// outputWriters are the parent's copies of the write ends of the output
+	// pipes, closed once the child started.
+	outputWriters []*os.File
+}
+
+func startProcess(c CommandConfig) (*process, error) {
+	p := &process{}
 	if dur := mustParseDurationField("duration", c.Duration, c.Name); dur > 0 {
-		ctx, cancel = context.WithTimeout(context.Background(), dur)
-		cmd = exec.CommandContext(ctx, c.Cmd, c.Args...) // #nosec G204 -- test tool with controlled config
+		p.ctx, p.cancel = context.WithTimeout(context.Background(), dur)
+		p.cmd = exec.CommandContext(p.ctx, c.Cmd, c.Args...) // #nosec G204 -- test tool with controlled config
 	} else {
-		cmd = exec.Command(c.Cmd, c.Args...) // #nosec G204 -- test tool with controlled config
+		p.cmd = exec.Command(c.Cmd, c.Args...) // #nosec G204 -- test tool with controlled config
 	}
-	if c.Env != nil {
-		env := os.Environ()
-		for k, v := range c.Env {
-			env = append(env, fmt.Sprintf("%s=%s", k, v))
+	p.cmd.Env = commandEnv(c)
+	fail := func(err error) (*process, error) {
+		p.releasePTY(false)
+		p.closeOutputWriters()
+		p.closeOutput()
+		if p.cancel != nil {
+			p.cancel()
 		}
-		cmd.Env = env
+		return nil, err
 	}
-	if c.Silent {
-		cmd.Stdout = io.Discard
-		cmd.Stderr = io.Discard
+	var err error
+	if c.PTY {
+		if err = attachPTY(p, c.Name, c.Stdin); err != nil {
+			return fail(err)
+		}
+	} else if c.Silent {
+		p.cmd.Stdout = io.Discard
+		p.cmd.Stderr = io.Discard
 	} else {
-		if stdout, err = cmd.StdoutPipe(); err != nil {
-			return nil, ctx, cancel, nil, nil, err
+		if p.stdout, err = p.outputPipe(&p.cmd.Stdout); err != nil {
+			return fail(err)
+		}
+		if p.stderr, err = p.outputPipe(&p.cmd.Stderr); err != nil {
+			return fail(err)
 		}
-		if stderr, err = cmd.StderrPipe(); err != nil {
-			return nil, ctx, cancel, nil, nil, err
+	}
+	if c.Stdin && !c.PTY {
+		if p.stdin, err = p.cmd.StdinPipe(); err != nil {
+			return fail(err)
 		}
 	}
-	if err = cmd.Start(); err != nil {
-		if cancel != nil {
-			cancel()
+	if err = p.cmd.Start(); err != nil {
+		return fail(err)
+	}
+	p.releasePTY(true)
+	p.closeOutputWriters()
+	return p, nil
+}
+
+// outputPipe connects an output stream of the command to a pipe. Unlike with
+// StdoutPipe, the read end stays open after Wait so that the output written
+// just before the exit can still be read; closeOutput closes it.
+func (p *process) outputPipe(stream *io.Writer) (io.ReadCloser, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	*stream = w
+	p.outputWriters = append(p.outputWriters, w)
+	return r, nil
+}
+
+func (p *process) closeOutputWriters() {
+	for _, w := range p.outputWriters {
+		_ = w.Close() //nolint:errcheck
+	}
+	p.outputWriters = nil
+}
+
+// drainOutput waits for the readers of the output pipes to reach the end of
+// the output, unless a child process keeps them open for longer than
+// outputDrainTimeout, and closes the pipes.
+func (p *process) drainOutput(readers *sync.WaitGroup) {
+	drained := make(chan struct{})
+	go func() {
+		readers.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(outputDrainTimeout):
+	}
+	p.closeOutput()
+}
+
+func (p *process) closeOutput() {
+	for _, r := range []io.ReadCloser{p.stdout, p.stderr} {
+		if r != nil {
+			_ = r.Close() //nolint:errcheck
 		}
-		return nil, ctx, nil, nil, nil, err
 	}
-	return cmd, ctx, cancel, stdout, stderr, nil
 }
 
-func executeOnce(c CommandConfig, identifier string, stdoutWriter, stderrWriter func(string), signals stopSignals, killTimeout time.Duration) (bool, bool, error) {
-	cmd, ctx, cancel, stdout, stderr, err := startProcess(c)
+// attemptResult describes how a single execution of a command ended.
+type attemptResult struct {
+	timedOut    bool
+	interrupted bool
+	err         error
+	runtime     time.Duration
+	// cpuTime is the CPU time used by the process and its reaped children.
+	cpuTime time.Duration
+	// action is the control request that ended the attempt, if any.
+	action string
+	// failure is the reason of the fail rule that ended the attempt, if any.
+	failure string
+}
+
+// failed returns the error ending the attempt: the fail rule match, wrapping
+// the wait error, or the wait error itself.
+func (res attemptResult) failed() error {
+	if res.failure == "" {
+		return res.err
+	}
+	return failureError(res.failure, res.err)
+}
+
+// runAttempt starts the command once, streams its output and waits for it to exit,
+// be interrupted or be ended by a control request. The attempt is reported to rec
+// when it is non-nil.
+func runAttempt(c CommandConfig, identifier string, stdoutWriter, stderrWriter func(string), signals stopSignals, control <-chan controlRequest, input *processInput, killTimeout time.Duration, rec *commandRecord) attemptResult {
+	rec.attemptStarting()
+	proc, err := startProcess(c)
 	if err != nil {
 		logCommandLine(stdoutWriter, stderrWriter, identifier, fmt.Sprintf("failed to start: %v", err))
-		return false, false, err
+		rec.startFailed()
+		return attemptResult{err: err}
 	}
-	if !c.Silent {
-		go streamOutput(stdoutWriter, stdout)
-		go streamOutput(stderrWriter, stderr)
+	cmd, ctx := proc.cmd, proc.ctx
+	started := time.Now()
+	rec.attemptStarted(cmd.Process.Pid)
+	if c.ReadyPattern == "" {
+		rec.markReady()
 	}
-	done := make(chan error, 1)
-	go func() { done <- cmd.Wait() }()
+	var readers sync.WaitGroup
+	switch {
+	case proc.pty != nil:
+		readers.Add(1)
+		go func() {
+			defer recoverPanic(c.Name + " output")
+			defer readers.Done()
+			// The master reports an error once the output of every process
+			// holding the terminal has been read.
+			if c.Silent {
+				streamOutput(nil, proc.pty)
+			} else {
+				streamOutput(stdoutWriter, proc.pty)
+			}
+			terminals.remove(proc.pty)
+			_ = proc.pty.Close() //nolint:errcheck
+		}()
+	case !c.Silent:
+		readers.Add(2)
+		go func() {
+			defer recoverPanic(c.Name + " stdout")
+			defer readers.Done()
+			streamOutput(stdoutWriter, proc.stdout)
+		}()
+		go func() {
+			defer recoverPanic(c.Name + " stderr")
+			defer readers.Done()
+			streamOutput(stderrWriter, proc.stderr)
+		}()
+	}
+	if proc.stdin != nil {
+		if input.attach(c.Name, proc.stdin) {
+			defer input.detach(c.Name, proc.stdin)
+		} else {
+			// Nobody can type into this process: let it see end of input.
+			_ = proc.stdin.Close() //nolint:errcheck
+		}
+	}
+	var waitErr error
+	done := make(chan error)
+	go func() {
+		defer recoverPanic(c.Name + " wait")
+		waitErr = cmd.Wait()
+		proc.drainOutput(&readers)
+		close(done)
+	}()
 	defer func() {
-		if cancel != nil {
-			cancel()
+		if proc.cancel != nil {
+			proc.cancel()
 		}
 	}()
 
-	select {
-	case err := <-done:
-		timedOut := ctx != nil && ctx.Err() == context.DeadlineExceeded
-		return timedOut, false, err
-	case <-signals.stop:
-		logCommandLine(stdoutWriter, stderrWriter, identifier, "interrupted")
-		terminateProcess(cmd, killTimeout, done, signals.immediate)
-		return false, true, nil
+	var res attemptResult
+wait:
+	for {
+		select {
+		case <-done:
+			res.timedOut = ctx != nil && ctx.Err() == context.DeadlineExceeded
+			break wait
+		case <-signals.stop:
+			logCommandLine(stdoutWriter, stderrWriter, identifier, "interrupted")
+			terminateProcess(cmd, killTimeout, done, signals.immediate)
+			<-done
+			res.interrupted = true
+			break wait
+		case req := <-control:
+			if req.action == actionStart {
+				continue // already running
+			}
+			logCommandLine(stdoutWriter, stderrWriter, identifier, fmt.Sprintf("%s requested: %s", req.action, req.reason))
+			if req.action == actionKill {
+				_ = cmd.Process.Kill() //nolint:errcheck
+			} else {
+				terminateProcess(cmd, killTimeout, done, signals.immediate)
+			}
+			<-done
+			res.action = req.action
+			if req.action == actionFail {
+				res.failure = req.reason
+			}
+			break wait
+		}
+	}
+	res.err = waitErr
+	res.runtime = time.Since(started)
+	if state := cmd.ProcessState; state != nil {
+		res.cpuTime = state.UserTime() + state.SystemTime()
+	}
+	rec.attemptFinished(res)
+	return res
+}
+
+// failureError reports a process ended by a fail rule as an error exit while
+// keeping the underlying wait error available to errors.As.
+func failureError(reason string, waitErr error) error {
+	if waitErr == nil {
+		return errors.New(reason)
 	}
+	return fmt.Errorf("%s: %w", reason, waitErr)
 }
 
 func logCommandOutcome(name string, err error, timedOut bool) {
@@ -134,8 +381,9 @@ func logCommandOutcome(name string, err error, timedOut bool) {
 	}
 }
 
-func handleNoRestart(name string, killOthers bool, alert *color.Color, requestStop func()) {
+func handleNoRestart(name string, killOthers bool, alert *color.Color, requestStop func(), rec *commandRecord) {
 	if killOthers {
+		rec.triggerKillOthers()
 		alert.Fprintf(errorOutput, "Stopping all processes due to killOnExit triggered by '%s'\n", name) //nolint:errcheck
 		if requestStop != nil {
 			requestStop()
@@ -152,42 +400,90 @@ func logRestartSchedule(name string, attempt int, restartTries int, triesLeft in
 	baseLog("[%s] scheduling restart (attempt %d)", name, attempt)
 }
 
-func runManagedCommand(c CommandConfig, col *color.Color, sink outputRouter, signals stopSignals, killTimeout time.Duration, killOthers bool, requestStop func()) {
+// runManagedCommand supervises a main command: it applies the restart policy,
+// handles killOthers and reacts to control requests. When resumable, a
+// stopped or finished command stays idle until it is started again, as a
+// watched one does until its files change.
+func runManagedCommand(c CommandConfig, col *color.Color, sink outputRouter, signals stopSignals, killTimeout time.Duration, killOthers bool, requestStop func(), rec *commandRecord, control chan controlRequest, input *processInput, resumable bool) {
+	if control == nil {
+		control = make(chan controlRequest, 1)
+	}
 	identifier := fmt.Sprintf("[%s] ", c.Name)
 	stdoutPrefix := identifier
 	stderrPrefix := fmt.Sprintf("[%s stderr] ", c.Name)
-	if _, isTUI := sink.(*tuiRouter); isTUI {
+	_, isTUI := sink.(*tuiRouter)
+	if isTUI {
 		stdoutPrefix = ""
 		stderrPrefix = "[stderr] "
 	}
 	stdoutWriter := sink.LineWriter(c.Name, col, stdoutPrefix)
 	stderrWriter := sink.LineWriter(c.Name, col, stderrPrefix)
+	if tui, ok := sink.(*tuiRouter); ok {
+		stderrWriter = tui.StreamWriter(c.Name, streamStderr, col, stderrPrefix)
+	}
+	onChangePrefix := fmt.Sprintf("[%s onChange] ", c.Name)
+	if isTUI {
+		onChangePrefix = "[onChange] "
+	}
+	onChangeWriter := sink.LineWriter(c.Name, col, onChangePrefix)
+	stdoutWriter, stderrWriter = instrumentOutput(c, rec, control, requestStop, stdoutWriter, stderrWriter)
 	alert := color.New(color.FgRed, color.Bold)
 	triesLeft := c.RestartTries
 	if waitStartDelay(c, signals.stop) {
 		baseLog("[%s] start aborted before launch", c.Name)
+		rec.markAborted()
 		return
 	}
 	baseLog("[%s] starting", c.Name)
+	defer watchFiles(c, control, onChangeWriter)()
+	if c.Schedule != nil {
+		runScheduledCommand(c, identifier, stdoutWriter, stderrWriter, signals, killTimeout, rec, control, input)
+		return
+	}
 	attempt := 1
 	for {
-		timedOut, interrupted, err := executeOnce(c, identifier, stdoutWriter, stderrWriter, signals, killTimeout)
-		if interrupted {
+		res := attemptResult{action: pendingStop(control)}
+		if res.action == "" {
+			res = runAttempt(c, identifier, stdoutWriter, stderrWriter, signals, control, input, killTimeout, rec)
+		}
+		if res.interrupted {
 			baseLog("[%s] interrupted", c.Name)
 			return
 		}
-		logCommandOutcome(c.Name, err, timedOut)
-		if !shouldRestart(err, timedOut, &triesLeft, c.RestartTries) {
-			handleNoRestart(c.Name, killOthers, alert, requestStop)
+		restart := false
+		switch res.action {
+		case actionStop, actionKill:
+			baseLog("[%s] stopped on request", c.Name)
+		case actionRemove:
+			baseLog("[%s] worker ended for a config reload", c.Name)
 			return
+		case actionRestart:
+			baseLog("[%s] restart requested", c.Name)
+			restart = true
+		default:
+			logCommandOutcome(c.Name, res.failed(), res.timedOut)
+			if shouldRestart(res.failed(), res.timedOut, &triesLeft, c.RestartTries) {
+				logRestartSchedule(c.Name, attempt+1, c.RestartTries, triesLeft)
+				rec.restartScheduled(attempt+1, c.RestartTries+1)
+				if waitRestartDelay(c, signals.stop) {
+					baseLog("[%s] restart aborted due to stop signal", c.Name)
+					rec.markAborted()
+					return
+				}
+				restart = true
+			} else {
+				handleNoRestart(c.Name, killOthers, alert, requestStop, rec)
+			}
 		}
-		attempt++
-		logRestartSchedule(c.Name, attempt, c.RestartTries, triesLeft)
-		if waitRestartDelay(c, signals.stop) {
-			baseLog("[%s] restart aborted due to stop signal", c.Name)
-			return
+		if !restart {
+			// A watched command waits for a change once it has ended.
+			if (!resumable && c.Watch == nil) || !waitForStart(c.Name, control, signals.stop) {
+				return
+			}
+			triesLeft = c.RestartTries
 		}
-		baseLog("[%s] restarting now", c.Name)
+		attempt++
+		baseLog("[%s] restarting now (attempt %d)", c.Name, attempt)
 	}
 }
 
@@ -237,14 +533,16 @@ func shouldRestart(err error, timedOut bool, triesLeft *int, restartTries int) b
 	return false
 }
 
-func runSetupWithRetries(c CommandConfig, identifier string, stdoutWriter, stderrWriter func(string)) bool {
+func runSetupWithRetries(c CommandConfig, identifier string, stdoutWriter, stderrWriter func(string), rec *commandRecord) bool {
+	stdoutWriter, stderrWriter = instrumentOutput(c, rec, nil, nil, stdoutWriter, stderrWriter)
 	triesLeft := c.RestartTries
 	for {
-		timedOut, _, err := executeOnce(c, identifier, stdoutWriter, stderrWriter, stopSignals{}, 0)
-		if err == nil || timedOut {
+		res := runAttempt(c, identifier, stdoutWriter, stderrWriter, stopSignals{}, nil, nil, 0, rec)
+		err := res.failed()
+		if err == nil || res.timedOut {
 			return true
 		}
-		if !shouldRestart(err, timedOut, &triesLeft, c.RestartTries) {
+		if !shouldRestart(err, res.timedOut, &triesLeft, c.RestartTries) {
 			return false
 		}
 		_ = waitRestartDelay(c, nil)
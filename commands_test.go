@@ -314,7 +314,7 @@ func TestStartProcess(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cmd, ctx, cancel, stdout, stderr, err := startProcess(tt.config)
+			proc, err := startProcess(tt.config)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("startProcess() error = %v, wantErr %v", err, tt.wantErr)
@@ -322,12 +322,13 @@ func TestStartProcess(t *testing.T) {
 			}
 
 			if err == nil {
+				stdout, stderr := proc.stdout, proc.stderr
 				defer func() {
-					if cmd != nil && cmd.Process != nil {
-						_ = cmd.Process.Kill()
+					if proc.cmd.Process != nil {
+						_ = proc.cmd.Process.Kill()
 					}
-					if cancel != nil {
-						cancel()
+					if proc.cancel != nil {
+						proc.cancel()
 					}
 				}()
 
@@ -347,7 +348,7 @@ func TestStartProcess(t *testing.T) {
 					}
 				}
 
-				if tt.config.Duration != "" && ctx == nil {
+				if tt.config.Duration != "" && proc.ctx == nil {
 					t.Error("expected context to be non-nil for command with duration")
 				}
 			}
@@ -355,13 +356,16 @@ func TestStartProcess(t *testing.T) {
 	}
 }
 
-func TestExecuteOnce(t *testing.T) {
+func TestRunManagedCommandOutcome(t *testing.T) {
+	origErrorOutput := errorOutput
+	errorOutput = io.Discard
+	defer func() { errorOutput = origErrorOutput }()
+
 	tests := []struct {
-		name         string
-		config       CommandConfig
-		sendStop     bool
-		wantTimedOut bool
-		wantStopped  bool
+		name      string
+		config    CommandConfig
+		sendStop  bool
+		wantState string
 	}{
 		{
 			name: "successful execution",
@@ -370,9 +374,7 @@ func TestExecuteOnce(t *testing.T) {
 				Cmd:  "echo",
 				Args: []string{"test"},
 			},
-			sendStop:     false,
-			wantTimedOut: false,
-			wantStopped:  false,
+			wantState: recordCompleted,
 		},
 		{
 			name: "command with timeout",
@@ -382,9 +384,7 @@ func TestExecuteOnce(t *testing.T) {
 				Args:     []string{"10"},
 				Duration: "50ms",
 			},
-			sendStop:     false,
-			wantTimedOut: true,
-			wantStopped:  false,
+			wantState: recordTimedOut,
 		},
 		{
 			name: "interrupted command",
@@ -393,27 +393,20 @@ func TestExecuteOnce(t *testing.T) {
 				Cmd:  "sleep",
 				Args: []string{"10"},
 			},
-			sendStop:     true,
-			wantTimedOut: false,
-			wantStopped:  true,
+			sendStop:  true,
+			wantState: recordInterrupted,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			stopCh := make(chan struct{})
-			immediateCh := make(chan struct{})
 			signals := stopSignals{
 				stop:      stopCh,
-				immediate: immediateCh,
-			}
-
-			var output bytes.Buffer
-			writeFunc := func(line string) {
-				output.WriteString(line + "\n")
+				immediate: make(chan struct{}),
 			}
+			rec := newRunSummary().Track(phaseMain, tt.config.Name)
 
-			done := make(chan bool, 1)
 			go func() {
 				if tt.sendStop {
 					time.Sleep(50 * time.Millisecond)
@@ -421,18 +414,16 @@ func TestExecuteOnce(t *testing.T) {
 				}
 			}()
 
+			done := make(chan struct{})
 			go func() {
-				timedOut, interrupted, _ := executeOnce(tt.config, "[test] ", writeFunc, writeFunc, signals, 100*time.Millisecond)
-				done <- timedOut
-				if interrupted != tt.wantStopped {
-					t.Errorf("interrupted = %v, wantStopped %v", interrupted, tt.wantStopped)
-				}
+				runManagedCommand(tt.config, nil, &recordingRouter{}, signals, 100*time.Millisecond, false, nil, rec, nil, nil, false)
+				close(done)
 			}()
 
 			select {
-			case timedOut := <-done:
-				if timedOut != tt.wantTimedOut {
-					t.Errorf("timedOut = %v, wantTimedOut %v", timedOut, tt.wantTimedOut)
+			case <-done:
+				if got := rec.snapshot().State; got != tt.wantState {
+					t.Errorf("state = %q, want %q", got, tt.wantState)
 				}
 			case <-time.After(2 * time.Second):
 				t.Fatal("test timed out")
@@ -441,6 +432,58 @@ func TestExecuteOnce(t *testing.T) {
 	}
 }
 
+func TestRunAttemptDrainsOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		runs    int
+		maxTime time.Duration
+	}{
+		{name: "output written before exit", args: []string{"-c", "echo first; echo last"}, want: "first,last", runs: 10, maxTime: time.Second},
+		{name: "child keeping the pipe open", args: []string{"-c", "sleep 10 & echo done"}, want: "done", runs: 1, maxTime: outputDrainTimeout + time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < tt.runs; i++ {
+				var mu sync.Mutex
+				var lines []string
+				write := func(line string) {
+					mu.Lock()
+					defer mu.Unlock()
+					lines = append(lines, line)
+				}
+				start := time.Now()
+				res := runAttempt(CommandConfig{Name: "drain", Cmd: "sh", Args: tt.args}, "[drain] ", write, write, stopSignals{}, nil, nil, 0, nil)
+				if res.err != nil {
+					t.Fatalf("runAttempt() error = %v", res.err)
+				}
+				if elapsed := time.Since(start); elapsed > tt.maxTime {
+					t.Fatalf("runAttempt() took %s, want at most %s", elapsed, tt.maxTime)
+				}
+				mu.Lock()
+				got := strings.Join(lines, ",")
+				mu.Unlock()
+				if got != tt.want {
+					t.Fatalf("run %d: output = %q, want %q", i, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunAttemptCPUTime(t *testing.T) {
+	// A busy loop shorter than any sampling interval, in a child of the shell.
+	c := CommandConfig{Name: "busy", Cmd: "sh", Args: []string{"-c", `sh -c 'end=$(($(date +%s) + 1)); while [ $(date +%s) -lt $end ]; do :; done'`}, Silent: true}
+	res := runAttempt(c, "", nil, nil, stopSignals{}, nil, nil, 0, nil)
+	if res.err != nil {
+		t.Fatalf("runAttempt() error = %v", res.err)
+	}
+	if res.cpuTime <= 0 {
+		t.Errorf("CPU time = %s, want the time used by the reaped child", res.cpuTime)
+	}
+}
+
 func TestLogCommandLine(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -582,10 +625,34 @@ func TestRunSetupWithRetries(t *testing.T) {
 				output.WriteString(line + "\n")
 			}
 
-			result := runSetupWithRetries(tt.config, "[test] ", writeFunc, writeFunc)
+			result := runSetupWithRetries(tt.config, "[test] ", writeFunc, writeFunc, nil)
 			if result != tt.shouldPass {
 				t.Errorf("runSetupWithRetries() = %v, want %v", result, tt.shouldPass)
 			}
 		})
 	}
 }
+
+func TestWatchReadiness(t *testing.T) {
+	summary := newRunSummary()
+	rec := summary.Track(phaseMain, "api")
+	c := CommandConfig{Name: "api", ReadyPattern: `listening on :\d+`}
+
+	var lines []string
+	writeFunc := func(line string) {
+		lines = append(lines, line)
+	}
+	stdoutWriter, _ := watchReadiness(c, rec, writeFunc, writeFunc)
+
+	stdoutWriter("booting")
+	if rows := summary.Snapshot(); rows[0].TimeToReadyMs != nil {
+		t.Fatal("expected command not to be ready before the pattern matches")
+	}
+	stdoutWriter("listening on :8080")
+	if rows := summary.Snapshot(); rows[0].TimeToReadyMs == nil {
+		t.Error("expected command to be ready after the pattern matched")
+	}
+	if len(lines) != 2 {
+		t.Errorf("expected lines to be forwarded, got %v", lines)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/go-playground/validator/v10"
 	"gopkg.in/yaml.v3"
 )
 
@@ -21,17 +23,44 @@ type CommandConfig struct {
 	StartAfter   string            `yaml:"startAfter"`
 	Silent       bool              `yaml:"silent"`
 	Duration     string            `yaml:"duration"`
+	ReadyPattern string            `yaml:"readyPattern"`
+	Filters      []FilterRule      `yaml:"filters" validate:"dive"`
+	OnOutput     []OutputRule      `yaml:"onOutput" validate:"dive"`
+	Colors       string            `yaml:"colors" validate:"omitempty,oneof=auto always never"`
+	StripANSI    bool              `yaml:"stripAnsi"`
+	Weight       int               `yaml:"weight" validate:"gte=0"`
+	Stdin        bool              `yaml:"stdin"`
+	PTY          bool              `yaml:"pty"`
+	Color        string            `yaml:"color"`
+	Watch        *WatchConfig      `yaml:"watch"`
+	Schedule     *ScheduleConfig   `yaml:"schedule"`
 }
 
 // Config aggregates the complete execution plan for the tool.
 type Config struct {
-	Commands         []CommandConfig `yaml:"commands" validate:"required,dive,required"`
-	KillOthers       bool            `yaml:"killOthers"`
-	KillTimeout      int             `yaml:"killTimeout"`
-	NoColors         bool            `yaml:"noColors"`
-	SetupCommands    []CommandConfig `yaml:"setupCommands"`
-	ShutdownCommands []CommandConfig `yaml:"shutdownCommands"`
-	EnableTUI        bool            `yaml:"enableTUI"`
+	Commands         []CommandConfig   `yaml:"commands" validate:"required,dive,required"`
+	KillOthers       bool              `yaml:"killOthers"`
+	KillTimeout      int               `yaml:"killTimeout"`
+	NoColors         bool              `yaml:"noColors"`
+	SetupCommands    []CommandConfig   `yaml:"setupCommands"`
+	ShutdownCommands []CommandConfig   `yaml:"shutdownCommands"`
+	EnableTUI        bool              `yaml:"enableTUI"`
+	TUILayout        string            `yaml:"tuiLayout" validate:"omitempty,oneof=grid rows columns tabs list zoom"`
+	Scrollback       int               `yaml:"scrollback" validate:"gte=-1"`
+	TUIKeepOpen      bool              `yaml:"tuiKeepOpen"`
+	ExportDir        string            `yaml:"exportDir"`
+	ExportOnExit     bool              `yaml:"exportOnExit"`
+	CopyLines        int               `yaml:"copyLines" validate:"gte=0"`
+	TUIStateFile     string            `yaml:"tuiStateFile"`
+	Keys             map[string]string `yaml:"keys"`
+	Stats            StatsConfig       `yaml:"stats"`
+	Theme            ThemeConfig       `yaml:"theme"`
+	InputTarget      string            `yaml:"inputTarget"`
+	Summary          bool              `yaml:"summary"`
+	SummaryFile      string            `yaml:"summaryFile"`
+	Report           ReportConfig      `yaml:"report"`
+	Filters          []FilterRule      `yaml:"filters" validate:"dive"`
+	ControlSocket    string            `yaml:"controlSocket"`
 }
 
 // loadConfig fully reads configuration data from the provided reader.
@@ -43,6 +72,87 @@ func loadConfig(r io.Reader) (Config, error) {
 	return cfg, nil
 }
 
+// loadConfigFile reads the configuration from the file at path.
+func loadConfigFile(path string) (Config, error) {
+	f, err := os.Open(path) // #nosec G304 -- path given by the user
+	if err != nil {
+		return Config{}, err
+	}
+	defer f.Close() //nolint:errcheck
+	return loadConfig(f)
+}
+
+// prepareConfig fills the defaults of a loaded config and validates all of it,
+// so that a config reloaded while running is rejected before anything changes.
+func prepareConfig(cfg *Config) error {
+	assignNames(cfg.Commands)
+	assignNames(cfg.SetupCommands)
+	assignNames(cfg.ShutdownCommands)
+	applyGlobalFilters(cfg.Filters, cfg.Commands)
+	applyGlobalFilters(cfg.Filters, cfg.SetupCommands)
+	applyGlobalFilters(cfg.Filters, cfg.ShutdownCommands)
+	if err := validator.New().Struct(cfg); err != nil {
+		return err
+	}
+	if _, err := newProcessInput(cfg.Commands, cfg.InputTarget); err != nil {
+		return err
+	}
+	if _, err := newTUITheme(cfg.Theme); err != nil {
+		return err
+	}
+	if err := validateCommandColors(cfg.Commands, cfg.SetupCommands, cfg.ShutdownCommands); err != nil {
+		return err
+	}
+	if _, err := newKeyMap(cfg.Keys); err != nil {
+		return err
+	}
+	if _, err := cfg.Stats.interval(); err != nil {
+		return err
+	}
+	for _, group := range [][]CommandConfig{cfg.SetupCommands, cfg.Commands, cfg.ShutdownCommands} {
+		for _, c := range group {
+			if err := checkCommand(c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkCommand reports the invalid durations, patterns, highlight colors,
+// watch globs and schedules of c, which would otherwise stop goncurrently when
+// the command starts.
+func checkCommand(c CommandConfig) error {
+	durations := [][2]string{{"startAfter", c.StartAfter}, {"restartAfter", c.RestartAfter}, {"duration", c.Duration}}
+	for _, field := range durations {
+		if field[1] == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(field[1]); err != nil {
+			return fmt.Errorf("invalid duration for %s in command '%s': %w", field[0], c.Name, err)
+		}
+	}
+	patterns := [][2]string{{"readyPattern", c.ReadyPattern}}
+	for _, rule := range c.Filters {
+		patterns = append(patterns, [2]string{"filters." + rule.Action, rule.Pattern})
+		if _, ok := highlightColors[strings.ToLower(rule.Color)]; rule.Action == filterHighlight && rule.Color != "" && !ok {
+			return fmt.Errorf("invalid highlight color '%s' in command '%s'", rule.Color, c.Name)
+		}
+	}
+	for _, rule := range c.OnOutput {
+		patterns = append(patterns, [2]string{"onOutput", rule.Pattern})
+	}
+	for _, field := range patterns {
+		if _, err := regexp.Compile(field[1]); err != nil {
+			return fmt.Errorf("invalid pattern for %s in command '%s': %w", field[0], c.Name, err)
+		}
+	}
+	if err := c.Watch.check(c.Name); err != nil {
+		return err
+	}
+	return c.Schedule.check(c.Name)
+}
+
 // assignNames fills missing command names with the executable basename.
 func assignNames(cmds []CommandConfig) {
 	for i := range cmds {
@@ -65,8 +175,19 @@ func mustParseDurationField(field string, value string, commandName string) time
 	}
 	d, err := time.ParseDuration(value)
 	if err != nil {
-		fmt.Fprintf(errorOutput, "invalid duration for %s in command '%s': %v\n", field, commandName, err) //nolint:errcheck
-		os.Exit(1)
+		fatalf("invalid duration for %s in command '%s': %v\n", field, commandName, err)
 	}
 	return d
 }
+
+// mustCompilePatternField compiles regular expression fields, terminating the process on invalid values.
+func mustCompilePatternField(field string, value string, commandName string) *regexp.Regexp {
+	if value == "" {
+		return nil
+	}
+	re, err := regexp.Compile(value)
+	if err != nil {
+		fatalf("invalid pattern for %s in command '%s': %v\n", field, commandName, err)
+	}
+	return re
+}
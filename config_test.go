@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 	"time"
 )
@@ -348,3 +349,38 @@ commands:
 		t.Error("expected enableTUI default to be false")
 	}
 }
+
+func TestPrepareConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{"valid", Config{Commands: []CommandConfig{{Cmd: "/usr/bin/sleep", StartAfter: "1s"}}}, ""},
+		{"missing cmd", Config{Commands: []CommandConfig{{Name: "api"}}}, "Cmd"},
+		{"invalid duration", Config{Commands: []CommandConfig{{Cmd: "sleep", RestartAfter: "soon"}}}, "restartAfter"},
+		{"invalid ready pattern", Config{Commands: []CommandConfig{{Cmd: "sleep", ReadyPattern: "("}}}, "readyPattern"},
+		{"invalid setup duration", Config{Commands: []CommandConfig{{Cmd: "sleep"}}, SetupCommands: []CommandConfig{{Cmd: "make", Duration: "x"}}}, "duration"},
+		{"invalid output rule", Config{Commands: []CommandConfig{{Cmd: "sleep", OnOutput: []OutputRule{{Pattern: "[", Action: actionRestart}}}}}, "onOutput"},
+		{"invalid watch", Config{Commands: []CommandConfig{{Cmd: "sleep", Watch: &WatchConfig{Debounce: "1"}}}}, "watch.debounce"},
+		{"invalid schedule", Config{Commands: []CommandConfig{{Cmd: "sleep", Schedule: &ScheduleConfig{Cron: "@often"}}}}, "schedule"},
+		{"invalid overlap", Config{Commands: []CommandConfig{{Cmd: "sleep", Schedule: &ScheduleConfig{Every: "1m", Overlap: "wait"}}}}, "Overlap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := prepareConfig(&tt.cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("prepareConfig() error = %v", err)
+				}
+				if tt.cfg.Commands[0].Name != "sleep" {
+					t.Errorf("name = %q, want the executable basename", tt.cfg.Commands[0].Name)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("prepareConfig() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
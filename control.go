@@ -0,0 +1,173 @@
+package main
+
+import (
+	"sync"
+)
+
+// Actions understood by the worker of a main command.
+const (
+	actionRestart = "restart"
+	actionStop    = "stop"
+	actionStart   = "start"
+	actionKill    = "kill"
+	actionFail    = "fail"
+	// actionRemove stops the command for good, when a reload removes or
+	// replaces it.
+	actionRemove = "remove"
+)
+
+// controlRequest asks the worker of a command to act on its current process.
+type controlRequest struct {
+	action string
+	reason string
+}
+
+// processControl routes control requests to the workers of main commands by name.
+type processControl struct {
+	mu       sync.Mutex
+	names    []string
+	channels map[string]chan controlRequest
+}
+
+func newProcessControl(names []string) *processControl {
+	pc := &processControl{
+		channels: make(map[string]chan controlRequest, len(names)),
+	}
+	for _, name := range names {
+		pc.register(name)
+	}
+	return pc
+}
+
+func (pc *processControl) register(name string) chan controlRequest {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if ch, ok := pc.channels[name]; ok {
+		return ch
+	}
+	ch := make(chan controlRequest, 1)
+	pc.channels[name] = ch
+	pc.names = append(pc.names, name)
+	return ch
+}
+
+// Channel returns the request channel consumed by the worker of name.
+func (pc *processControl) Channel(name string) chan controlRequest {
+	if pc == nil {
+		return nil
+	}
+	return pc.register(name)
+}
+
+// Names returns the controllable commands in registration order.
+func (pc *processControl) Names() []string {
+	if pc == nil {
+		return nil
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return append([]string(nil), pc.names...)
+}
+
+// Send delivers a request to the worker of name without blocking. It reports
+// false when the command is unknown or a request is already pending.
+func (pc *processControl) Send(name, action, reason string) bool {
+	if pc == nil {
+		return false
+	}
+	pc.mu.Lock()
+	ch, ok := pc.channels[name]
+	pc.mu.Unlock()
+	if !ok {
+		return false
+	}
+	return sendControl(ch, controlRequest{action: action, reason: reason})
+}
+
+// Deliver sends a request to the worker of name, waiting for the pending one
+// to be consumed. It reports false when the command is unknown or cancel is
+// closed first.
+func (pc *processControl) Deliver(name string, req controlRequest, cancel <-chan struct{}) bool {
+	pc.mu.Lock()
+	ch, ok := pc.channels[name]
+	pc.mu.Unlock()
+	if !ok {
+		return false
+	}
+	select {
+	case ch <- req:
+		return true
+	case <-cancel:
+		return false
+	}
+}
+
+// Unregister forgets the worker of name, once it has ended.
+func (pc *processControl) Unregister(name string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if _, ok := pc.channels[name]; !ok {
+		return
+	}
+	delete(pc.channels, name)
+	for i, registered := range pc.names {
+		if registered == name {
+			pc.names = append(pc.names[:i], pc.names[i+1:]...)
+			break
+		}
+	}
+}
+
+// SendAll delivers the same request to every registered worker.
+func (pc *processControl) SendAll(action, reason string) {
+	for _, name := range pc.Names() {
+		pc.Send(name, action, reason)
+	}
+}
+
+func sendControl(ch chan<- controlRequest, req controlRequest) bool {
+	select {
+	case ch <- req:
+		return true
+	default:
+		return false
+	}
+}
+
+// pendingStop consumes the requests queued while no process was running, such
+// as during a restart delay. It returns the stop, kill or remove action among
+// them so the worker does not start a process the user already asked to stop.
+func pendingStop(control <-chan controlRequest) string {
+	action := ""
+	for {
+		select {
+		case req := <-control:
+			if action != actionRemove && (req.action == actionStop || req.action == actionKill || req.action == actionRemove) {
+				action = req.action
+			}
+		default:
+			return action
+		}
+	}
+}
+
+// waitForStart parks a stopped command until a start or restart request
+// arrives. It reports false when the global stop signal fires first or the
+// command is removed.
+func waitForStart(name string, control <-chan controlRequest, stop <-chan struct{}) bool {
+	baseLog("[%s] idle, waiting for start", name)
+	for {
+		select {
+		case <-stop:
+			return false
+		case req := <-control:
+			if req.action == actionRemove {
+				return false
+			}
+			if req.action == actionStart || req.action == actionRestart {
+				baseLog("[%s] %s requested: %s", name, req.action, req.reason)
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// logHistoryLines is the number of recent output lines returned before
+	// the streamed ones.
+	logHistoryLines = 1000
+	// logSubscriberBuffer is the number of lines a slow log client may lag
+	// behind before lines are dropped for it.
+	logSubscriberBuffer = 256
+	controlShutdownWait = time.Second
+)
+
+// logLine is an output line of a command, as streamed by the control server.
+type logLine struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Stream  string    `json:"stream"`
+	Line    string    `json:"line"`
+}
+
+// logHub keeps the recent output lines of every command and fans them out
+// to the log clients of the control server.
+type logHub struct {
+	mu          sync.Mutex
+	history     ring[logLine]
+	subscribers map[chan logLine]string
+}
+
+func newLogHub(limit int) *logHub {
+	return &logHub{
+		history:     ring[logLine]{limit: limit},
+		subscribers: make(map[chan logLine]string),
+	}
+}
+
+// publish records a line and sends it to the matching subscribers. It never
+// blocks: a subscriber whose buffer is full misses the line.
+func (h *logHub) publish(command, stream, text string) {
+	if h == nil {
+		return
+	}
+	line := logLine{Time: time.Now(), Command: command, Stream: stream, Line: text}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.history.push(line)
+	for ch, name := range h.subscribers {
+		if name != "" && name != command {
+			continue
+		}
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// Subscribe returns the recent lines of command, or of every command when it
+// is empty, and a channel receiving the following ones until cancel is called.
+func (h *logHub) Subscribe(command string, follow bool) ([]logLine, <-chan logLine, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var recent []logLine
+	for _, line := range h.history.snapshot() {
+		if command == "" || line.Command == command {
+			recent = append(recent, line)
+		}
+	}
+	if !follow {
+		return recent, nil, func() {}
+	}
+	ch := make(chan logLine, logSubscriberBuffer)
+	h.subscribers[ch] = command
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers, ch)
+	}
+	return recent, ch, cancel
+}
+
+// commandState is a command as listed by the control server.
+type commandState struct {
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	Running   bool       `json:"running"`
+	PID       int        `json:"pid,omitempty"`
+	Attempts  int        `json:"attempts"`
+	Restarts  int        `json:"restarts"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	UptimeMs  int64      `json:"uptimeMs,omitempty"`
+	Runs      int        `json:"runs,omitempty"`
+	NextRunAt *time.Time `json:"nextRunAt,omitempty"`
+}
+
+func newCommandState(ev statusEvent, now time.Time) commandState {
+	state := commandState{
+		Name:     ev.Name,
+		State:    ev.Label,
+		Running:  ev.Running,
+		Attempts: ev.Attempt,
+		Restarts: ev.Restarts,
+		Runs:     ev.Runs,
+	}
+	if ev.Label == statusScheduled {
+		nextRun := ev.NextRun
+		state.NextRunAt = &nextRun
+	}
+	if ev.Running {
+		startedAt := ev.StartedAt
+		state.PID = ev.PID
+		state.StartedAt = &startedAt
+		state.UptimeMs = now.Sub(startedAt).Milliseconds()
+	}
+	return state
+}
+
+// controlServer serves the control API on a Unix socket. Only the users
+// allowed by the socket permissions, the owner by default, can connect.
+type controlServer struct {
+	path   string
+	server *http.Server
+	done   chan struct{}
+}
+
+// startControlServer listens on the socket at path and serves the commands
+// of sup and the lines of logs until Close.
+func startControlServer(path string, sup *supervisor, logs *logHub) (*controlServer, error) {
+	listener, err := listenControlSocket(path)
+	if err != nil {
+		return nil, err
+	}
+	closing := make(chan struct{})
+	s := &controlServer{
+		path: path,
+		server: &http.Server{
+			Handler:           controlHandler(sup, logs, closing),
+			ReadHeaderTimeout: 5 * time.Second,
+			ErrorLog:          log.New(errorOutput, "control socket: ", 0),
+		},
+		done: make(chan struct{}),
+	}
+	s.server.RegisterOnShutdown(func() { close(closing) })
+	go func() {
+		defer recoverPanic("control server")
+		defer close(s.done)
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			baseLog("control socket stopped: %v", err)
+		}
+	}()
+	baseLog("control socket listening on %s", path)
+	return s, nil
+}
+
+// listenControlSocket listens on path, replacing the socket left by a run
+// that did not exit cleanly, and restricts it to the owner.
+func listenControlSocket(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("control socket %s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close() //nolint:errcheck
+			return nil, fmt.Errorf("control socket %s is used by another instance", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale control socket: %w", err)
+		}
+	}
+	// The socket is created with the umask permissions, so it is bound in a
+	// private directory and only moved into place once restricted.
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".goncurrently-")
+	if err != nil {
+		return nil, fmt.Errorf("create control socket: %w", err)
+	}
+	defer os.RemoveAll(dir) //nolint:errcheck
+	bound := filepath.Join(dir, "s")
+	listener, err := net.Listen("unix", bound)
+	if err != nil {
+		return nil, fmt.Errorf("listen on control socket: %w", err)
+	}
+	// Close removes the socket at its final path instead.
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(bound, 0o600); err != nil {
+		listener.Close() //nolint:errcheck
+		return nil, fmt.Errorf("restrict control socket: %w", err)
+	}
+	if err := os.Rename(bound, path); err != nil {
+		listener.Close() //nolint:errcheck
+		return nil, fmt.Errorf("create control socket: %w", err)
+	}
+	return listener, nil
+}
+
+// Close stops the server, ending the streamed logs, and removes the socket.
+func (s *controlServer) Close() {
+	if s == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), controlShutdownWait)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.server.Close() //nolint:errcheck
+	}
+	<-s.done
+	os.Remove(s.path) //nolint:errcheck
+}
+
+// controlHandler routes the endpoints of the control API:
+//
+//	GET  /commands                  list the main commands
+//	GET  /logs?command=&follow=1    recent output lines as NDJSON, then new ones with follow
+//	POST /commands/{name}/{action}  restart, stop, start or kill a command
+//	POST /stop                      stop every command and end the run
+//	POST /reload                    reload the config file, answering the applied plan
+//
+// Streamed logs end when closing is closed.
+func controlHandler(sup *supervisor, logs *logHub, closing <-chan struct{}) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /commands", func(w http.ResponseWriter, _ *http.Request) {
+		now := time.Now()
+		states := []commandState{}
+		for _, ev := range sup.Commands() {
+			states = append(states, newCommandState(ev, now))
+		}
+		writeJSON(w, http.StatusOK, states)
+	})
+	mux.HandleFunc("POST /commands/{name}/{action}", func(w http.ResponseWriter, r *http.Request) {
+		name, action := r.PathValue("name"), r.PathValue("action")
+		err := sup.Control(name, action, "control socket")
+		switch {
+		case errors.Is(err, errUnknownCommand):
+			writeError(w, http.StatusNotFound, err)
+		case errors.Is(err, errUnknownAction):
+			writeError(w, http.StatusBadRequest, err)
+		case errors.Is(err, errControlBusy):
+			writeError(w, http.StatusConflict, err)
+		default:
+			writeJSON(w, http.StatusAccepted, map[string]string{"command": name, "action": action})
+		}
+	})
+	mux.HandleFunc("POST /stop", func(w http.ResponseWriter, _ *http.Request) {
+		sup.StopAll("control socket")
+		writeJSON(w, http.StatusAccepted, map[string]string{"action": "stop"})
+	})
+	mux.HandleFunc("POST /reload", func(w http.ResponseWriter, _ *http.Request) {
+		plan, err := sup.Reload("control socket")
+		switch {
+		case errors.Is(err, errInvalidConfig):
+			writeError(w, http.StatusUnprocessableEntity, err)
+		case err != nil:
+			writeError(w, http.StatusConflict, err)
+		default:
+			writeJSON(w, http.StatusOK, plan)
+		}
+	})
+	mux.HandleFunc("GET /logs", func(w http.ResponseWriter, r *http.Request) {
+		streamLogs(w, r, sup, logs, closing)
+	})
+	return mux
+}
+
+// streamLogs writes the recent lines as NDJSON and, with follow, the new ones
+// until the client disconnects or closing is closed.
+func streamLogs(w http.ResponseWriter, r *http.Request, sup *supervisor, logs *logHub, closing <-chan struct{}) {
+	command := r.URL.Query().Get("command")
+	if command != "" && !sup.has(command) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w %q", errUnknownCommand, command))
+		return
+	}
+	follow := r.URL.Query().Get("follow") != ""
+	recent, lines, cancel := logs.Subscribe(command, follow)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	for _, line := range recent {
+		if enc.Encode(line) != nil {
+			return
+		}
+	}
+	if !follow {
+		return
+	}
+	flusher, _ := w.(http.Flusher)
+	for {
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-closing:
+			return
+		case line := <-lines:
+			if enc.Encode(line) != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogHub(t *testing.T) {
+	hub := newLogHub(3)
+	hub.publish("api", streamStdout, "one")
+	hub.publish("web", streamStdout, "two")
+	hub.publish("api", streamStderr, "three")
+	hub.publish("api", streamStdout, "four")
+
+	recent, lines, cancel := hub.Subscribe("api", true)
+	if got := logTexts(recent); got != "three,four" {
+		t.Errorf("recent api lines = %q, want the ones kept in history", got)
+	}
+	hub.publish("web", streamStdout, "five")
+	hub.publish("api", streamStdout, "six")
+	select {
+	case line := <-lines:
+		if line.Command != "api" || line.Line != "six" {
+			t.Errorf("followed line = %+v", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the new api line")
+	}
+	cancel()
+	hub.publish("api", streamStdout, "seven")
+	select {
+	case line := <-lines:
+		t.Errorf("received %+v after cancel", line)
+	default:
+	}
+
+	all, _, _ := hub.Subscribe("", false)
+	if got := logTexts(all); got != "five,six,seven" {
+		t.Errorf("recent lines = %q", got)
+	}
+}
+
+func TestLogHubDropsForSlowSubscribers(t *testing.T) {
+	hub := newLogHub(1)
+	_, lines, cancel := hub.Subscribe("", true)
+	defer cancel()
+	for range logSubscriberBuffer + 10 {
+		hub.publish("api", streamStdout, "line")
+	}
+	if len(lines) != logSubscriberBuffer {
+		t.Errorf("buffered %d lines, want %d", len(lines), logSubscriberBuffer)
+	}
+}
+
+func TestCaptureOutputPublishesLogs(t *testing.T) {
+	hub := newLogHub(10)
+	summary := newRunSummary()
+	summary.StreamLogs(hub)
+	rec := summary.Track(phaseMain, "api")
+	stdout, stderr := captureOutput(rec, func(string) {}, func(string) {})
+	stdout("\x1b[32mready\x1b[0m")
+	stderr("oops")
+
+	recent, _, _ := hub.Subscribe("api", false)
+	if len(recent) != 2 || recent[0].Line != "ready" || recent[1].Stream != streamStderr {
+		t.Errorf("published lines = %+v", recent)
+	}
+	if rows := summary.Snapshot(); rows[0].StdoutTail != nil {
+		t.Errorf("tails must stay disabled, got %v", rows[0].StdoutTail)
+	}
+}
+
+func logTexts(lines []logLine) string {
+	texts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		texts = append(texts, line.Line)
+	}
+	return strings.Join(texts, ",")
+}
+
+func TestControlServer(t *testing.T) {
+	origErrorOutput := errorOutput
+	errorOutput = io.Discard
+	defer func() { errorOutput = origErrorOutput }()
+
+	path := filepath.Join(t.TempDir(), "ctl.sock")
+	stopped := make(chan struct{}, 1)
+	sup := newTestSupervisor("api", "web")
+	sup.requestStop = func() { stopped <- struct{}{} }
+	logs := newLogHub(10)
+	logs.publish("api", streamStdout, "listening")
+	logs.publish("web", streamStdout, "compiled")
+
+	server, err := startControlServer(path, sup, logs)
+	if err != nil {
+		t.Fatalf("startControlServer() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("socket missing: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket permissions = %o, want 600", perm)
+	}
+	client := newControlClient(path, true)
+
+	resp, err := client.Get("http://goncurrently/commands")
+	if err != nil {
+		t.Fatalf("GET /commands: %v", err)
+	}
+	var states []commandState
+	if err := json.NewDecoder(resp.Body).Decode(&states); err != nil {
+		t.Fatalf("decode commands: %v", err)
+	}
+	resp.Body.Close() //nolint:errcheck
+	if len(states) != 2 || states[0].Name != "api" || states[1].Name != "web" {
+		t.Errorf("commands = %+v", states)
+	}
+
+	posts := []struct {
+		path string
+		want int
+	}{
+		{"/commands/api/restart", http.StatusAccepted},
+		{"/commands/api/stop", http.StatusConflict},
+		{"/commands/db/stop", http.StatusNotFound},
+		{"/commands/web/pause", http.StatusBadRequest},
+		{"/reload", http.StatusConflict},
+		{"/stop", http.StatusAccepted},
+	}
+	for _, tt := range posts {
+		resp, err := client.Post("http://goncurrently"+tt.path, "application/json", nil)
+		if err != nil {
+			t.Fatalf("POST %s: %v", tt.path, err)
+		}
+		resp.Body.Close() //nolint:errcheck
+		if resp.StatusCode != tt.want {
+			t.Errorf("POST %s = %d, want %d", tt.path, resp.StatusCode, tt.want)
+		}
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("POST /stop did not request the stop")
+	}
+
+	resp, err = client.Get("http://goncurrently/logs?command=api&follow=1")
+	if err != nil {
+		t.Fatalf("GET /logs: %v", err)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	var line logLine
+	if !scanner.Scan() || json.Unmarshal(scanner.Bytes(), &line) != nil || line.Line != "listening" {
+		t.Fatalf("first log line = %q", scanner.Text())
+	}
+	logs.publish("web", streamStdout, "ignored")
+	logs.publish("api", streamStderr, "request failed")
+	if !scanner.Scan() || json.Unmarshal(scanner.Bytes(), &line) != nil || line.Line != "request failed" {
+		t.Fatalf("followed log line = %q", scanner.Text())
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		server.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(controlShutdownWait / 2):
+		t.Fatal("Close() waited for the followed logs")
+	}
+	resp.Body.Close() //nolint:errcheck
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket not removed on close: %v", err)
+	}
+}
+
+func TestListenControlSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "file.sock")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenControlSocket(file); err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("expected a regular file to be kept, got %v", err)
+	}
+
+	path := filepath.Join(dir, "ctl.sock")
+	live, err := listenControlSocket(path)
+	if err != nil {
+		t.Fatalf("listenControlSocket() error = %v", err)
+	}
+	if _, err := listenControlSocket(path); err == nil || !strings.Contains(err.Error(), "another instance") {
+		t.Errorf("expected a live socket to be kept, got %v", err)
+	}
+	live.(*net.UnixListener).SetUnlinkOnClose(false)
+	live.Close() //nolint:errcheck
+
+	stale, err := listenControlSocket(path)
+	if err != nil {
+		t.Fatalf("stale socket not replaced: %v", err)
+	}
+	stale.Close() //nolint:errcheck
+
+	// The private directory the socket is bound in does not outlive it.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("directory holds %d entries, want the file and the socket", len(entries))
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func TestProcessControlSend(t *testing.T) {
+	pc := newProcessControl([]string{"api", "web"})
+
+	if !pc.Send("api", actionRestart, "test") {
+		t.Fatal("expected first request to be delivered")
+	}
+	if pc.Send("api", actionStop, "test") {
+		t.Error("expected second request to be rejected while one is pending")
+	}
+	if pc.Send("missing", actionStop, "test") {
+		t.Error("expected unknown command to be rejected")
+	}
+
+	req := <-pc.Channel("api")
+	if req.action != actionRestart {
+		t.Errorf("unexpected request %+v", req)
+	}
+
+	pc.SendAll(actionStart, "all")
+	for _, name := range pc.Names() {
+		select {
+		case req := <-pc.Channel(name):
+			if req.action != actionStart {
+				t.Errorf("%s: unexpected request %+v", name, req)
+			}
+		default:
+			t.Errorf("%s: expected a start request", name)
+		}
+	}
+}
+
+func TestNilProcessControl(t *testing.T) {
+	var pc *processControl
+	if pc.Send("api", actionStop, "test") {
+		t.Error("nil control must not deliver requests")
+	}
+	pc.SendAll(actionStop, "test")
+	if pc.Channel("api") != nil || pc.Names() != nil {
+		t.Error("nil control must not expose channels")
+	}
+}
+
+func TestPendingStop(t *testing.T) {
+	control := make(chan controlRequest, 1)
+	if got := pendingStop(control); got != "" {
+		t.Errorf("pendingStop() on empty channel = %q", got)
+	}
+	control <- controlRequest{action: actionRestart}
+	if got := pendingStop(control); got != "" {
+		t.Errorf("pendingStop() with restart = %q, want empty", got)
+	}
+	control <- controlRequest{action: actionKill}
+	if got := pendingStop(control); got != actionKill {
+		t.Errorf("pendingStop() = %q, want %q", got, actionKill)
+	}
+	control <- controlRequest{action: actionRemove}
+	if got := pendingStop(control); got != actionRemove {
+		t.Errorf("pendingStop() = %q, want %q", got, actionRemove)
+	}
+}
+
+func TestProcessControlDeliver(t *testing.T) {
+	pc := newProcessControl([]string{"api", "web"})
+	pc.Send("api", actionRestart, "test")
+
+	delivered := make(chan bool, 1)
+	go func() {
+		delivered <- pc.Deliver("api", controlRequest{action: actionRemove}, nil)
+	}()
+	if req := <-pc.Channel("api"); req.action != actionRestart {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if !<-delivered {
+		t.Error("expected the request to be delivered once the pending one was consumed")
+	}
+	if req := <-pc.Channel("api"); req.action != actionRemove {
+		t.Errorf("unexpected request %+v", req)
+	}
+
+	cancel := make(chan struct{})
+	close(cancel)
+	pc.Send("web", actionStop, "test")
+	if pc.Deliver("web", controlRequest{action: actionRemove}, cancel) {
+		t.Error("expected a canceled delivery to fail")
+	}
+
+	pc.Unregister("api")
+	if names := pc.Names(); len(names) != 1 || names[0] != "web" {
+		t.Errorf("Names() = %v after Unregister", names)
+	}
+	if pc.Deliver("api", controlRequest{action: actionRemove}, nil) {
+		t.Error("expected an unregistered command to be rejected")
+	}
+}
+
+func TestWaitForStart(t *testing.T) {
+	origErrorOutput := errorOutput
+	errorOutput = &bytes.Buffer{}
+	defer func() {
+		errorOutput = origErrorOutput
+	}()
+
+	control := make(chan controlRequest, 1)
+	stop := make(chan struct{})
+	result := make(chan bool, 1)
+	go func() {
+		result <- waitForStart("api", control, stop)
+	}()
+
+	control <- controlRequest{action: actionStop}
+	control <- controlRequest{action: actionStart}
+	select {
+	case ok := <-result:
+		if !ok {
+			t.Error("expected start request to resume the command")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waitForStart did not return after a start request")
+	}
+
+	control <- controlRequest{action: actionRemove}
+	if waitForStart("api", control, stop) {
+		t.Error("expected a removal to end the wait")
+	}
+
+	close(stop)
+	if waitForStart("api", control, stop) {
+		t.Error("expected global stop to end the wait")
+	}
+}
+
+type recordingRouter struct {
+	consoleRouter
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *recordingRouter) LineWriter(_ string, _ *color.Color, prefix string) func(string) {
+	return func(line string) {
+		r.mu.Lock()
+		r.lines = append(r.lines, prefix+line)
+		r.mu.Unlock()
+	}
+}
+
+func TestRunManagedCommandControl(t *testing.T) {
+	origErrorOutput := errorOutput
+	var logs bytes.Buffer
+	var logsMu sync.Mutex
+	errorOutput = writerFunc(func(p []byte) (int, error) {
+		logsMu.Lock()
+		defer logsMu.Unlock()
+		return logs.Write(p)
+	})
+	defer func() {
+		errorOutput = origErrorOutput
+	}()
+
+	summary := newRunSummary()
+	rec := summary.Track(phaseMain, "sleeper")
+	control := make(chan controlRequest, 1)
+	c := CommandConfig{Name: "sleeper", Cmd: "sleep", Args: []string{"10"}}
+	done := make(chan struct{})
+	go func() {
+		runManagedCommand(c, nil, &recordingRouter{}, stopSignals{}, 100*time.Millisecond, false, nil, rec, control, nil, false)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	control <- controlRequest{action: actionRestart, reason: "test"}
+	time.Sleep(100 * time.Millisecond)
+	control <- controlRequest{action: actionStop, reason: "test"}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("worker did not stop after a stop request")
+	}
+
+	row := summary.Snapshot()[0]
+	if row.Attempts != 2 || row.State != recordStopped {
+		t.Errorf("unexpected record after restart and stop: %+v", row)
+	}
+	logsMu.Lock()
+	defer logsMu.Unlock()
+	if !strings.Contains(logs.String(), "[sleeper] restart requested") {
+		t.Errorf("expected restart to be logged, got:\n%s", logs.String())
+	}
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
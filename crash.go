@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"sync"
+)
+
+var (
+	// exit and crashOutput are replaced by tests.
+	exit                  = os.Exit
+	crashOutput io.Writer = os.Stderr
+
+	exitMu      sync.Mutex
+	exitCleanup func()
+	crashOnce   sync.Once
+)
+
+// setExitCleanup registers the function restoring the terminal before the
+// process exits abnormally, such as closing the TUI.
+func setExitCleanup(cleanup func()) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitCleanup = cleanup
+}
+
+// runExitCleanup runs the registered cleanup once.
+func runExitCleanup() {
+	exitMu.Lock()
+	cleanup := exitCleanup
+	exitCleanup = nil
+	exitMu.Unlock()
+	if cleanup != nil {
+		cleanup()
+	}
+}
+
+// fatalf restores the terminal, then prints the message on stderr, where it
+// stays visible once the TUI is gone, and exits with status 1.
+func fatalf(format string, args ...any) {
+	runExitCleanup()
+	fmt.Fprintf(crashOutput, format, args...) //nolint:errcheck
+	exit(1)
+}
+
+// recoverPanic is deferred at the top of every goroutine: a panic restores the
+// terminal before the panic and its stack are printed, and exits with status 2.
+// Panics in other goroutines meanwhile wait for the exit.
+func recoverPanic(where string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	crashOnce.Do(func() {
+		runExitCleanup()
+		fmt.Fprintf(crashOutput, "panic in %s: %v\n\n%s", where, r, stack) //nolint:errcheck
+		exit(2)
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// captureExit replaces the exit of the process for a test and returns the
+// output and the exit codes.
+func captureExit(t *testing.T) (*bytes.Buffer, *[]int) {
+	t.Helper()
+	origExit, origOutput := exit, crashOutput
+	var buf bytes.Buffer
+	var codes []int
+	exit = func(code int) { codes = append(codes, code) }
+	crashOutput = &buf
+	crashOnce = sync.Once{}
+	t.Cleanup(func() {
+		exit, crashOutput = origExit, origOutput
+		setExitCleanup(nil)
+	})
+	return &buf, &codes
+}
+
+func TestRecoverPanic(t *testing.T) {
+	buf, codes := captureExit(t)
+	var events []string
+	setExitCleanup(func() { events = append(events, "cleanup:"+buf.String()) })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer recoverPanic("api worker")
+		panic("boom")
+	}()
+	<-done
+
+	if len(*codes) != 1 || (*codes)[0] != 2 {
+		t.Fatalf("exit codes = %v, want [2]", *codes)
+	}
+	if len(events) != 1 || events[0] != "cleanup:" {
+		t.Errorf("cleanup = %v, want it to run once before the output", events)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "panic in api worker: boom\n") || !strings.Contains(out, "crash_test.go") {
+		t.Errorf("output = %q, want the panic and its stack", out)
+	}
+}
+
+func TestRecoverPanicWithoutPanic(t *testing.T) {
+	_, codes := captureExit(t)
+	func() {
+		defer recoverPanic("idle")
+	}()
+	if len(*codes) != 0 {
+		t.Errorf("exit codes = %v, want none", *codes)
+	}
+}
+
+func TestFatalf(t *testing.T) {
+	buf, codes := captureExit(t)
+	restored := false
+	setExitCleanup(func() { restored = true })
+
+	fatalf("invalid duration for %s\n", "startAfter")
+	if !restored || buf.String() != "invalid duration for startAfter\n" {
+		t.Errorf("fatalf() restored = %t, output = %q", restored, buf.String())
+	}
+	if len(*codes) != 1 || (*codes)[0] != 1 {
+		t.Errorf("exit codes = %v, want [1]", *codes)
+	}
+}
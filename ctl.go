@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// defaultControlSocket is the socket name found by the client commands
+	// in the current directory or its parents.
+	defaultControlSocket = ".goncurrently.sock"
+	ctlRequestTimeout    = 5 * time.Second
+)
+
+// ctlCommands are the subcommands talking to a running instance.
+var ctlCommands = map[string]bool{
+	"ps":       true,
+	"restart":  true,
+	"stop":     true,
+	"start":    true,
+	"logs":     true,
+	"stop-all": true,
+	"reload":   true,
+}
+
+// errCtlUsage reports invalid arguments of a client command.
+var errCtlUsage = errors.New("usage")
+
+// ctlOptions are the arguments of a client command.
+type ctlOptions struct {
+	command string
+	socket  string
+	config  string
+	follow  bool
+	args    []string
+}
+
+// parseCtlArgs parses the arguments following the subcommand. Flags may
+// appear before or after the command name.
+func parseCtlArgs(command string, args []string) (ctlOptions, error) {
+	opts := ctlOptions{command: command}
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.socket, "socket", "", "control socket of the instance")
+	fs.StringVar(&opts.config, "config", "", "config file whose controlSocket is used")
+	if command == "logs" {
+		fs.BoolVar(&opts.follow, "f", false, "follow new lines")
+	}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return opts, fmt.Errorf("%w: %v", errCtlUsage, err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		opts.args = append(opts.args, args[0])
+		args = args[1:]
+	}
+	want := 0
+	switch command {
+	case "restart", "stop", "start":
+		want = 1
+	case "logs":
+		if len(opts.args) <= 1 {
+			return opts, nil
+		}
+	}
+	if len(opts.args) != want {
+		return opts, fmt.Errorf("%w: %s", errCtlUsage, ctlUsage(command))
+	}
+	return opts, nil
+}
+
+func ctlUsage(command string) string {
+	switch command {
+	case "restart", "stop", "start":
+		return fmt.Sprintf("goncurrently %s [--socket path] <name>", command)
+	case "logs":
+		return "goncurrently logs [-f] [--socket path] [name]"
+	}
+	return fmt.Sprintf("goncurrently %s [--socket path]", command)
+}
+
+// controlSocketPath resolves a relative controlSocket from the directory of
+// the config file, or from the current directory when the config is read
+// from stdin, so that the server and the client agree on it.
+func controlSocketPath(socket, configPath string) string {
+	if configPath == "" || filepath.IsAbs(socket) {
+		return socket
+	}
+	return filepath.Join(filepath.Dir(configPath), socket)
+}
+
+// findControlSocket returns the socket of the instance to control: the one
+// given with --socket, the controlSocket of the --config file, resolved
+// from its directory, or the default socket in the current directory or the
+// nearest parent having one.
+func findControlSocket(opts ctlOptions) (string, error) {
+	if opts.socket != "" {
+		return opts.socket, nil
+	}
+	if opts.config != "" {
+		cfg, err := loadConfigFile(opts.config)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", opts.config, err)
+		}
+		if cfg.ControlSocket == "" {
+			return "", fmt.Errorf("%s does not set controlSocket", opts.config)
+		}
+		return controlSocketPath(cfg.ControlSocket, opts.config), nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, defaultControlSocket)
+		if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no %s found in the current directory or its parents, use --socket", defaultControlSocket)
+		}
+		dir = parent
+	}
+}
+
+// newControlClient returns an HTTP client connected to the control socket at
+// path, without a timeout when it waits for streamed logs or a reload.
+func newControlClient(path string, wait bool) *http.Client {
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	if !wait {
+		client.Timeout = ctlRequestTimeout
+	}
+	return client
+}
+
+// runCtl runs a client subcommand and returns the exit status: 0 on success,
+// 1 on errors and 2 on invalid arguments.
+func runCtl(command string, args []string, stdout, stderr io.Writer) int {
+	opts, err := parseCtlArgs(command, args)
+	if err == nil {
+		err = ctl(opts, stdout, stderr)
+	}
+	if err == nil {
+		return 0
+	}
+	fmt.Fprintf(stderr, "goncurrently %s: %v\n", command, err) //nolint:errcheck
+	if errors.Is(err, errCtlUsage) {
+		return 2
+	}
+	return 1
+}
+
+func ctl(opts ctlOptions, stdout, stderr io.Writer) error {
+	socket, err := findControlSocket(opts)
+	if err != nil {
+		return err
+	}
+	client := newControlClient(socket, opts.follow || opts.command == "reload")
+	switch opts.command {
+	case "ps":
+		return ctlPS(client, stdout)
+	case "restart", "stop", "start":
+		name := opts.args[0]
+		if err := ctlPost(client, "/commands/"+url.PathEscape(name)+"/"+opts.command); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s: %s requested\n", name, opts.command) //nolint:errcheck
+		return nil
+	case "stop-all":
+		if err := ctlPost(client, "/stop"); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, "stop requested") //nolint:errcheck
+		return nil
+	case "reload":
+		return ctlReload(client, stdout)
+	case "logs":
+		name := ""
+		if len(opts.args) > 0 {
+			name = opts.args[0]
+		}
+		return ctlLogs(client, name, opts.follow, stdout, stderr)
+	}
+	return fmt.Errorf("%w: unknown command %q", errCtlUsage, opts.command)
+}
+
+// ctlGet sends a GET request and checks its status.
+func ctlGet(client *http.Client, path string) (*http.Response, error) {
+	resp, err := client.Get("http://goncurrently" + path)
+	if err != nil {
+		return nil, fmt.Errorf("no running instance: %w", err)
+	}
+	if err := controlError(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func ctlPost(client *http.Client, path string) error {
+	resp, err := client.Post("http://goncurrently"+path, "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("no running instance: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	return controlError(resp)
+}
+
+// controlError returns the error answered by the control server, closing the
+// response body in that case.
+func controlError(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&body) != nil || body.Error == "" {
+		return fmt.Errorf("control server answered %s", resp.Status)
+	}
+	return errors.New(body.Error)
+}
+
+// ctlReload reloads the config of the instance and prints the applied plan.
+func ctlReload(client *http.Client, w io.Writer) error {
+	resp, err := client.Post("http://goncurrently/reload", "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("no running instance: %w", err)
+	}
+	if err := controlError(resp); err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	var plan reloadPlan
+	if err := json.NewDecoder(resp.Body).Decode(&plan); err != nil {
+		return fmt.Errorf("decode reload plan: %w", err)
+	}
+	fmt.Fprintf(w, "reloaded: %s\n", plan) //nolint:errcheck
+	return nil
+}
+
+// ctlPS prints the commands of the instance as a table.
+func ctlPS(client *http.Client, w io.Writer) error {
+	resp, err := ctlGet(client, "/commands")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	var states []commandState
+	if err := json.NewDecoder(resp.Body).Decode(&states); err != nil {
+		return fmt.Errorf("decode commands: %w", err)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATE\tPID\tRESTARTS\tUPTIME") //nolint:errcheck
+	for _, s := range states {
+		pid, uptime := "-", "-"
+		if s.Running {
+			pid = fmt.Sprint(s.PID)
+			uptime = formatUptime(time.Duration(s.UptimeMs) * time.Millisecond)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", s.Name, s.State, pid, s.Restarts, uptime) //nolint:errcheck
+	}
+	return tw.Flush()
+}
+
+// ctlLogs prints the recent lines of name, or of every command prefixed
+// with its name, then the new ones with follow. stderr lines go to stderr.
+func ctlLogs(client *http.Client, name string, follow bool, stdout, stderr io.Writer) error {
+	query := url.Values{}
+	if name != "" {
+		query.Set("command", name)
+	}
+	if follow {
+		query.Set("follow", "1")
+	}
+	resp, err := ctlGet(client, "/logs?"+query.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var line logLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return fmt.Errorf("decode log line: %w", err)
+		}
+		out := stdout
+		if line.Stream == streamStderr {
+			out = stderr
+		}
+		if name == "" {
+			fmt.Fprintf(out, "[%s] %s\n", line.Command, line.Line) //nolint:errcheck
+		} else {
+			fmt.Fprintln(out, line.Line) //nolint:errcheck
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCtlArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+		want    ctlOptions
+		wantErr bool
+	}{
+		{"ps", "ps", nil, ctlOptions{command: "ps"}, false},
+		{"socket before name", "restart", []string{"--socket", "/tmp/x.sock", "api"}, ctlOptions{command: "restart", socket: "/tmp/x.sock", args: []string{"api"}}, false},
+		{"socket after name", "stop", []string{"api", "--socket=/tmp/x.sock"}, ctlOptions{command: "stop", socket: "/tmp/x.sock", args: []string{"api"}}, false},
+		{"logs follow", "logs", []string{"-f", "api"}, ctlOptions{command: "logs", follow: true, args: []string{"api"}}, false},
+		{"logs of all", "logs", []string{"--config", "dev.yaml"}, ctlOptions{command: "logs", config: "dev.yaml"}, false},
+		{"missing name", "start", nil, ctlOptions{}, true},
+		{"extra name", "stop-all", []string{"api"}, ctlOptions{}, true},
+		{"two logs names", "logs", []string{"api", "web"}, ctlOptions{}, true},
+		{"follow only for logs", "ps", []string{"-f"}, ctlOptions{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCtlArgs(tt.command, tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseCtlArgs(%q, %v) = %+v, want an error", tt.command, tt.args, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCtlArgs() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCtlArgs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestControlSocketPath(t *testing.T) {
+	tests := []struct {
+		socket, config, want string
+	}{
+		{".goncurrently.sock", "", ".goncurrently.sock"},
+		{".goncurrently.sock", filepath.Join("sub", "r.yaml"), filepath.Join("sub", ".goncurrently.sock")},
+		{"/run/gc.sock", filepath.Join("sub", "r.yaml"), "/run/gc.sock"},
+	}
+	for _, tt := range tests {
+		if got := controlSocketPath(tt.socket, tt.config); got != tt.want {
+			t.Errorf("controlSocketPath(%q, %q) = %q, want %q", tt.socket, tt.config, got, tt.want)
+		}
+	}
+}
+
+func TestFindControlSocket(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "dev.yaml")
+	if err := os.WriteFile(config, []byte("controlSocket: ./run/ctl.sock\ncommands: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	noSocket := filepath.Join(dir, "plain.yaml")
+	if err := os.WriteFile(noSocket, []byte("commands: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := findControlSocket(ctlOptions{socket: "/tmp/given.sock", config: config}); got != "/tmp/given.sock" {
+		t.Errorf("--socket must win, got %q", got)
+	}
+	if got, _ := findControlSocket(ctlOptions{config: config}); got != filepath.Join(dir, "run", "ctl.sock") {
+		t.Errorf("config socket = %q, want it resolved from the config directory", got)
+	}
+	if _, err := findControlSocket(ctlOptions{config: noSocket}); err == nil {
+		t.Error("expected an error for a config without controlSocket")
+	}
+
+	listener, err := net.Listen("unix", filepath.Join(dir, defaultControlSocket))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close() //nolint:errcheck
+	nested := filepath.Join(dir, "services", "api")
+	if err := os.MkdirAll(nested, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(nested)
+	if got, err := findControlSocket(ctlOptions{}); err != nil || got != filepath.Join(dir, defaultControlSocket) {
+		t.Errorf("findControlSocket() = %q, %v, want the socket of a parent", got, err)
+	}
+}
+
+func TestRunCtl(t *testing.T) {
+	origErrorOutput := errorOutput
+	errorOutput = io.Discard
+	defer func() { errorOutput = origErrorOutput }()
+
+	path := filepath.Join(t.TempDir(), "ctl.sock")
+	stopped := false
+	sup := newTestSupervisor("api", "web")
+	sup.requestStop = func() { stopped = true }
+	logs := newLogHub(10)
+	logs.publish("api", streamStdout, "listening")
+	logs.publish("web", streamStderr, "deprecated flag")
+	server, err := startControlServer(path, sup, logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		command    string
+		args       []string
+		wantStatus int
+		wantOut    string
+		wantErr    string
+	}{
+		{"ps", "ps", nil, 0, "NAME  STATE    PID  RESTARTS  UPTIME\napi   waiting  -    0         -\nweb   waiting  -    0         -\n", ""},
+		{"restart", "restart", []string{"api"}, 0, "api: restart requested\n", ""},
+		{"busy", "stop", []string{"api"}, 1, "", "a request is already pending"},
+		{"unknown command", "start", []string{"db"}, 1, "", `unknown command "db"`},
+		{"logs of one command", "logs", []string{"api"}, 0, "listening\n", ""},
+		{"logs of all", "logs", nil, 0, "[api] listening\n", "[web] deprecated flag"},
+		{"usage", "restart", nil, 2, "", "usage: goncurrently restart"},
+		{"stop-all", "stop-all", nil, 0, "stop requested\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"--socket", path}, tt.args...)
+			status := runCtl(tt.command, args, &stdout, &stderr)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d (stderr %q)", status, tt.wantStatus, stderr.String())
+			}
+			if stdout.String() != tt.wantOut {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.wantOut)
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.wantErr)
+			}
+		})
+	}
+	if !stopped {
+		t.Error("stop-all did not stop the run")
+	}
+
+	var stderr bytes.Buffer
+	if status := runCtl("ps", []string{"--socket", path + ".missing"}, io.Discard, &stderr); status != 1 || !strings.Contains(stderr.String(), "no running instance") {
+		t.Errorf("missing socket: status %d, stderr %q", status, stderr.String())
+	}
+}
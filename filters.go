@@ -0,0 +1,180 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+const (
+	filterDrop      = "drop"
+	filterKeepOnly  = "keep-only"
+	filterHighlight = "highlight"
+	filterRedact    = "redact"
+
+	redactedText = "***"
+)
+
+// FilterRule describes a regex-based transformation applied to each output line.
+type FilterRule struct {
+	Action  string `yaml:"action" validate:"oneof=drop keep-only highlight redact"`
+	Pattern string `yaml:"pattern" validate:"required"`
+	Color   string `yaml:"color"`
+}
+
+// outputFilter is the compiled form of a command's filter rules.
+type outputFilter struct {
+	drop      []*regexp.Regexp
+	keepOnly  []*regexp.Regexp
+	redact    []*regexp.Regexp
+	highlight []highlightRule
+}
+
+type highlightRule struct {
+	pattern *regexp.Regexp
+	color   *color.Color
+}
+
+var highlightColors = map[string]color.Attribute{
+	"black":   color.FgBlack,
+	"red":     color.FgRed,
+	"green":   color.FgGreen,
+	"yellow":  color.FgYellow,
+	"blue":    color.FgBlue,
+	"magenta": color.FgMagenta,
+	"cyan":    color.FgCyan,
+	"white":   color.FgWhite,
+}
+
+// applyGlobalFilters prepends the global filter rules to every command's own rules.
+func applyGlobalFilters(global []FilterRule, cmds []CommandConfig) {
+	if len(global) == 0 {
+		return
+	}
+	for i := range cmds {
+		rules := make([]FilterRule, 0, len(global)+len(cmds[i].Filters))
+		rules = append(rules, global...)
+		rules = append(rules, cmds[i].Filters...)
+		cmds[i].Filters = rules
+	}
+}
+
+// mustCompileFilters compiles filter rules, terminating the process on invalid patterns or colors.
+func mustCompileFilters(rules []FilterRule, commandName string) *outputFilter {
+	if len(rules) == 0 {
+		return nil
+	}
+	f := &outputFilter{}
+	for _, rule := range rules {
+		pattern := mustCompilePatternField("filters."+rule.Action, rule.Pattern, commandName)
+		if pattern == nil {
+			continue
+		}
+		switch rule.Action {
+		case filterDrop:
+			f.drop = append(f.drop, pattern)
+		case filterKeepOnly:
+			f.keepOnly = append(f.keepOnly, pattern)
+		case filterRedact:
+			f.redact = append(f.redact, pattern)
+		case filterHighlight:
+			attr, ok := highlightColors[strings.ToLower(rule.Color)]
+			if rule.Color == "" {
+				attr, ok = color.FgYellow, true
+			}
+			if !ok {
+				fatalf("invalid highlight color '%s' in command '%s'\n", rule.Color, commandName)
+			}
+			f.highlight = append(f.highlight, highlightRule{pattern: pattern, color: color.New(attr, color.Bold)})
+		default:
+			fatalf("invalid filter action '%s' in command '%s'\n", rule.Action, commandName)
+		}
+	}
+	return f
+}
+
+// apply evaluates drop and keep-only rules against the raw line and redacts
+// secrets. It reports false when the line must not be emitted.
+func (f *outputFilter) apply(line string) (string, bool) {
+	if f == nil {
+		return line, true
+	}
+	for _, re := range f.drop {
+		if re.MatchString(line) {
+			return "", false
+		}
+	}
+	if len(f.keepOnly) > 0 && !matchesAny(f.keepOnly, line) {
+		return "", false
+	}
+	return f.redactSecrets(line), true
+}
+
+// redactSecrets masks the matches of redact rules.
+func (f *outputFilter) redactSecrets(line string) string {
+	if f == nil {
+		return line
+	}
+	for _, re := range f.redact {
+		line = re.ReplaceAllLiteralString(line, redactedText)
+	}
+	return line
+}
+
+// decorate colors the matches of highlight rules.
+func (f *outputFilter) decorate(line string) string {
+	if f == nil {
+		return line
+	}
+	for _, rule := range f.highlight {
+		line = rule.pattern.ReplaceAllStringFunc(line, func(match string) string {
+			return rule.color.Sprint(match)
+		})
+	}
+	return line
+}
+
+func matchesAny(patterns []*regexp.Regexp, line string) bool {
+	for _, re := range patterns {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterOutput wraps the writers so that dropped lines never reach them and
+// redacted lines are forwarded with secrets masked.
+func filterOutput(f *outputFilter, stdoutWriter, stderrWriter func(string)) (func(string), func(string)) {
+	if f == nil {
+		return stdoutWriter, stderrWriter
+	}
+	wrap := func(writeLine func(string)) func(string) {
+		if writeLine == nil {
+			return nil
+		}
+		return func(line string) {
+			if filtered, ok := f.apply(line); ok {
+				writeLine(filtered)
+			}
+		}
+	}
+	return wrap(stdoutWriter), wrap(stderrWriter)
+}
+
+// highlightOutput wraps the writers so that highlight rules color matching text.
+func highlightOutput(f *outputFilter, stdoutWriter, stderrWriter func(string)) (func(string), func(string)) {
+	if f == nil || len(f.highlight) == 0 {
+		return stdoutWriter, stderrWriter
+	}
+	wrap := func(writeLine func(string)) func(string) {
+		if writeLine == nil {
+			return nil
+		}
+		return func(line string) {
+			writeLine(f.decorate(line))
+		}
+	}
+	return wrap(stdoutWriter), wrap(stderrWriter)
+}
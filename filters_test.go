@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestOutputFilterApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []FilterRule
+		line     string
+		wantLine string
+		wantKeep bool
+	}{
+		{
+			name:     "no rules",
+			rules:    nil,
+			line:     "hello",
+			wantLine: "hello",
+			wantKeep: true,
+		},
+		{
+			name:     "drop matching line",
+			rules:    []FilterRule{{Action: filterDrop, Pattern: `GET /health`}},
+			line:     "GET /health 200",
+			wantKeep: false,
+		},
+		{
+			name:     "keep-only drops other lines",
+			rules:    []FilterRule{{Action: filterKeepOnly, Pattern: `ERROR|WARN`}},
+			line:     "INFO started",
+			wantKeep: false,
+		},
+		{
+			name:     "keep-only keeps matching lines",
+			rules:    []FilterRule{{Action: filterKeepOnly, Pattern: `ERROR|WARN`}},
+			line:     "WARN slow query",
+			wantLine: "WARN slow query",
+			wantKeep: true,
+		},
+		{
+			name:     "redact secrets",
+			rules:    []FilterRule{{Action: filterRedact, Pattern: `token=\S+`}},
+			line:     "login token=abc123 ok",
+			wantLine: "login *** ok",
+			wantKeep: true,
+		},
+		{
+			name: "drop wins over keep-only",
+			rules: []FilterRule{
+				{Action: filterKeepOnly, Pattern: `ERROR`},
+				{Action: filterDrop, Pattern: `ignored`},
+			},
+			line:     "ERROR ignored",
+			wantKeep: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mustCompileFilters(tt.rules, "test")
+			got, keep := f.apply(tt.line)
+			if keep != tt.wantKeep {
+				t.Fatalf("apply() keep = %v, want %v", keep, tt.wantKeep)
+			}
+			if keep && got != tt.wantLine {
+				t.Errorf("apply() = %q, want %q", got, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestOutputFilterDecorate(t *testing.T) {
+	origNoColor := color.NoColor
+	color.NoColor = false
+	defer func() {
+		color.NoColor = origNoColor
+	}()
+
+	f := mustCompileFilters([]FilterRule{{Action: filterHighlight, Pattern: `ERROR`, Color: "red"}}, "test")
+	got := f.decorate("an ERROR happened")
+	if !strings.Contains(got, "\x1b[31;1mERROR") {
+		t.Errorf("decorate() = %q, expected highlighted match", got)
+	}
+}
+
+func TestApplyGlobalFilters(t *testing.T) {
+	global := []FilterRule{{Action: filterRedact, Pattern: `secret`}}
+	cmds := []CommandConfig{
+		{Name: "a"},
+		{Name: "b", Filters: []FilterRule{{Action: filterDrop, Pattern: `noise`}}},
+	}
+	applyGlobalFilters(global, cmds)
+
+	if len(cmds[0].Filters) != 1 || cmds[0].Filters[0].Action != filterRedact {
+		t.Errorf("unexpected filters for a: %+v", cmds[0].Filters)
+	}
+	if len(cmds[1].Filters) != 2 || cmds[1].Filters[0].Action != filterRedact || cmds[1].Filters[1].Action != filterDrop {
+		t.Errorf("expected global rules first for b: %+v", cmds[1].Filters)
+	}
+}
+
+func TestInstrumentOutputRedactsCapturedTail(t *testing.T) {
+	summary := newRunSummary()
+	summary.CaptureOutput(5)
+	rec := summary.Track(phaseMain, "api")
+	c := CommandConfig{
+		Name: "api",
+		Filters: []FilterRule{
+			{Action: filterRedact, Pattern: `password=\S+`},
+			{Action: filterDrop, Pattern: `healthz`},
+		},
+	}
+
+	var lines []string
+	writeFunc := func(line string) {
+		lines = append(lines, line)
+	}
+	stdoutWriter, _ := instrumentOutput(c, rec, nil, nil, writeFunc, writeFunc)
+	stdoutWriter("GET /healthz")
+	stdoutWriter("connecting password=hunter2")
+
+	if len(lines) != 1 || lines[0] != "connecting ***" {
+		t.Errorf("unexpected forwarded lines: %v", lines)
+	}
+	tail := summary.Snapshot()[0].StdoutTail
+	if len(tail) != 1 || tail[0] != "connecting ***" {
+		t.Errorf("expected redacted tail, got %v", tail)
+	}
+}
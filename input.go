@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// processInput routes text typed by the user to the stdin of running main
+// commands that have stdin enabled.
+type processInput struct {
+	mu            sync.Mutex
+	enabled       map[string]bool
+	pipes         map[string]io.WriteCloser
+	defaultTarget string
+}
+
+// newProcessInput registers the commands accepting input. Lines without a
+// target go to defaultTarget, or to the first command with stdin enabled.
+func newProcessInput(commands []CommandConfig, defaultTarget string) (*processInput, error) {
+	pi := &processInput{
+		enabled: make(map[string]bool),
+		pipes:   make(map[string]io.WriteCloser),
+	}
+	for _, c := range commands {
+		if !c.Stdin {
+			continue
+		}
+		pi.enabled[c.Name] = true
+		if pi.defaultTarget == "" {
+			pi.defaultTarget = c.Name
+		}
+	}
+	if defaultTarget != "" {
+		if !pi.enabled[defaultTarget] {
+			return nil, fmt.Errorf("inputTarget '%s' is not a command with stdin enabled", defaultTarget)
+		}
+		pi.defaultTarget = defaultTarget
+	}
+	return pi, nil
+}
+
+// Enabled reports whether any command accepts input.
+func (pi *processInput) Enabled() bool {
+	return pi != nil && len(pi.enabled) > 0
+}
+
+// accepts reports whether name has stdin enabled.
+func (pi *processInput) accepts(name string) bool {
+	return pi.Enabled() && pi.enabled[name]
+}
+
+// attach connects the stdin of the running process of name. It reports false
+// when no input is routed to the command.
+func (pi *processInput) attach(name string, w io.WriteCloser) bool {
+	if pi == nil {
+		return false
+	}
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+	if !pi.enabled[name] {
+		return false
+	}
+	pi.pipes[name] = w
+	return true
+}
+
+// detach disconnects w unless a newer process already replaced it.
+func (pi *processInput) detach(name string, w io.WriteCloser) {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+	if pi.pipes[name] == w {
+		delete(pi.pipes, name)
+	}
+}
+
+// Send writes text followed by a newline to the stdin of name.
+func (pi *processInput) Send(name, text string) error {
+	if !pi.accepts(name) {
+		return fmt.Errorf("%s does not accept input (set stdin: true)", name)
+	}
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+	w := pi.pipes[name]
+	if w == nil {
+		return fmt.Errorf("%s is not running", name)
+	}
+	if _, err := io.WriteString(w, text+"\n"); err != nil {
+		return fmt.Errorf("write to %s: %w", name, err)
+	}
+	return nil
+}
+
+// Route sends a console line: "name:text" goes to the command name, any other
+// line to the default target.
+func (pi *processInput) Route(line string) error {
+	if name, text, ok := strings.Cut(line, ":"); ok && pi.accepts(name) {
+		return pi.Send(name, text)
+	}
+	if pi.defaultTarget == "" {
+		return errors.New("no command accepts input")
+	}
+	return pi.Send(pi.defaultTarget, line)
+}
+
+// readConsoleInput routes every line read from r until it is exhausted.
+func readConsoleInput(r io.Reader, input *processInput) {
+	defer recoverPanic("console input")
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if err := input.Route(scanner.Text()); err != nil {
+			baseLog("input not delivered: %v", err)
+		}
+	}
+}
+
+// openConsoleInput returns the terminal the user types in. The configuration
+// usually arrives on stdin, so the controlling terminal is preferred.
+func openConsoleInput() (io.ReadCloser, error) {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return nil, fmt.Errorf("open terminal for input: %w", err)
+	}
+	return tty, nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// bufferPipe is an in-memory stand-in for the stdin pipe of a process.
+type bufferPipe struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (p *bufferPipe) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.buf.Write(b)
+}
+
+func (p *bufferPipe) Close() error { return nil }
+
+func (p *bufferPipe) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.buf.String()
+}
+
+func TestNewProcessInput(t *testing.T) {
+	commands := []CommandConfig{{Name: "db"}, {Name: "api", Stdin: true}, {Name: "web", Stdin: true}}
+	tests := []struct {
+		name    string
+		target  string
+		want    string
+		wantErr bool
+	}{
+		{name: "first stdin command by default", want: "api"},
+		{name: "configured target", target: "web", want: "web"},
+		{name: "target without stdin", target: "db", wantErr: true},
+		{name: "unknown target", target: "nope", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := newProcessInput(commands, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newProcessInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && input.defaultTarget != tt.want {
+				t.Errorf("defaultTarget = %q, want %q", input.defaultTarget, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessInputRoute(t *testing.T) {
+	input, err := newProcessInput([]CommandConfig{{Name: "api", Stdin: true}, {Name: "web", Stdin: true}, {Name: "db"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	api, web := &bufferPipe{}, &bufferPipe{}
+	input.attach("api", api)
+
+	if err := input.Route("web:reload"); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Errorf("expected an error for a command that is not running, got %v", err)
+	}
+	input.attach("web", web)
+	for _, line := range []string{"rs", "web:r", "db:x", "a:b"} {
+		if err := input.Route(line); err != nil {
+			t.Errorf("Route(%q) error = %v", line, err)
+		}
+	}
+	if got := api.String(); got != "rs\ndb:x\na:b\n" {
+		t.Errorf("api stdin = %q", got)
+	}
+	if got := web.String(); got != "r\n" {
+		t.Errorf("web stdin = %q", got)
+	}
+	if err := input.Send("db", "x"); err == nil {
+		t.Error("expected an error for a command without stdin")
+	}
+
+	input.detach("web", &bufferPipe{})
+	if err := input.Send("web", "still attached"); err != nil {
+		t.Errorf("detaching another pipe must keep the current one: %v", err)
+	}
+}
+
+func TestRunAttemptStdin(t *testing.T) {
+	c := CommandConfig{Name: "echo", Cmd: "sh", Args: []string{"-c", `read line; test "$line" = hello`}, Stdin: true}
+	input, err := newProcessInput([]CommandConfig{c}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan attemptResult, 1)
+	go func() {
+		done <- runAttempt(c, "[echo] ", nil, nil, stopSignals{}, nil, input, time.Second, nil)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for input.Send("echo", "hello") != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("process stdin was never attached")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if res := <-done; res.err != nil {
+		t.Fatalf("runAttempt() error = %v", res.err)
+	}
+	if err := input.Send("echo", "late"); err == nil {
+		t.Error("expected the pipe to be detached after exit")
+	}
+}
+
+func TestReadConsoleInput(t *testing.T) {
+	input, err := newProcessInput([]CommandConfig{{Name: "api", Stdin: true}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipe := &bufferPipe{}
+	input.attach("api", pipe)
+	readConsoleInput(strings.NewReader("one\napi:two\n"), input)
+	if got := pipe.String(); got != "one\ntwo\n" {
+		t.Errorf("api stdin = %q", got)
+	}
+}
+
+func TestTUIRouterInput(t *testing.T) {
+	input, err := newProcessInput([]CommandConfig{{Name: "api", Stdin: true}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipe := &bufferPipe{}
+	input.attach("api", pipe)
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{})
+	router.BindInput(input)
+	waitForScreen(t, router, screen, "api ● waiting")
+
+	screen.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'i', tcell.ModNone)
+	waitForScreen(t, router, screen, "api> ")
+	for _, r := range "rs" {
+		screen.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for pipe.String() != "rs\n" {
+		if time.Now().After(deadline) {
+			t.Fatalf("api stdin = %q, want %q", pipe.String(), "rs\n")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	screen.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	waitForScreenWithout(t, router, screen, "api> ")
+}
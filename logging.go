@@ -14,9 +14,16 @@ const (
 
 var errorOutput io.Writer = os.Stderr
 
+// lifecycleOutput receives baseLog messages when set, so the TUI can hide them.
+var lifecycleOutput io.Writer
+
 func baseLog(format string, args ...any) {
 	if !strings.HasSuffix(format, "\n") {
 		format += "\n"
 	}
-	fmt.Fprintf(errorOutput, format, args...) //nolint:errcheck
+	out := errorOutput
+	if lifecycleOutput != nil {
+		out = lifecycleOutput
+	}
+	fmt.Fprintf(out, format, args...) //nolint:errcheck
 }
@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/fatih/color"
-	"github.com/go-playground/validator/v10"
 )
 
 // Version is the current version of goncurrently.
@@ -22,14 +21,28 @@ func printHelp() {
 Usage:
   cat config.yaml | goncurrently
   goncurrently < config.yaml
+  goncurrently --config config.yaml
   goncurrently --help
   goncurrently --version
+  goncurrently <control command> [--socket path | --config file]
 
 Commands:
   --help, -h       Show this help message
   --version, -v    Show version information
+  --config, -c     Read the configuration from a file, which SIGHUP reloads
 
-Configuration (via YAML on stdin):
+Control commands (talk to a running instance with controlSocket set):
+  ps                 List the commands with their state, pid, restarts and uptime
+  restart <name>     Restart a command
+  stop <name>        Stop a command
+  start <name>       Start a stopped command
+  logs [-f] [name]   Print the recent output of a command, or of all, -f to follow
+  stop-all           Stop every command and end the run
+  reload             Reload the config file of an instance started with --config
+  The instance is found with --socket, with the controlSocket of --config, or
+  as .goncurrently.sock in the current directory or a parent.
+
+Configuration (via YAML on stdin or --config):
   commands           List of commands to run concurrently (required)
   setupCommands      Commands to run sequentially before main commands
   shutdownCommands   Commands to run sequentially after all main commands complete
@@ -37,6 +50,23 @@ Configuration (via YAML on stdin):
   killTimeout        Timeout in milliseconds before force kill (default: 0)
   noColors           Disable colored output (default: false)
   enableTUI          Enable terminal UI mode (default: false)
+  tuiLayout          TUI layout: grid, rows, columns, tabs, list or zoom (default: grid)
+  scrollback         Lines kept per TUI panel, -1 for unlimited (default: 10000)
+  tuiKeepOpen        Keep the TUI open after the run until q or Enter is pressed
+  exportDir          Directory where TUI panels are saved (default: current directory)
+  exportOnExit       Save every TUI panel to exportDir when goncurrently exits
+  copyLines          Lines copied to the clipboard with y when nothing is selected (default: 100)
+  tuiStateFile       File keeping the panel sizes set with the mouse between runs
+  keys               TUI keys remapped by action name, e.g. {restart: x, quit: q}
+  stats              Sample CPU and memory of commands: {enabled, interval (default: 2s), pane}
+  theme              TUI colors: preset (dark, light, high-contrast), base, border, focusedBorder,
+                     background, text, status, statusBackground
+  inputTarget        Command receiving console input lines without a "name:" prefix
+  summary            Print an end-of-run summary table on stderr (default: false)
+  summaryFile        Write the end-of-run summary as JSON to this path
+  report             Test reports written after the run (junit, tap, tailLines)
+  filters            Output filter rules applied to every command
+  controlSocket      Unix socket serving the control API, e.g. ./.goncurrently.sock
 
 Command Configuration:
   name               Name of the command (auto-generated if not provided)
@@ -48,6 +78,17 @@ Command Configuration:
   env                Environment variables (map)
   silent             Suppress command output (default: false)
   duration           Maximum execution time
+  readyPattern       Regex marking the command as ready when matched on its output
+  filters            Output filter rules (action: drop|keep-only|highlight|redact, pattern, color)
+  onOutput           Actions on matching output (pattern, stream, action: restart|stop|fail|stopAll|hook, run)
+  colors             Child color output: auto, always or never (default: auto)
+  stripAnsi          Remove ANSI escape sequences from the output (default: false)
+  weight             Relative size of the command's TUI panel (default: 1)
+  stdin              Forward typed input to the command's stdin (default: false)
+  pty                Run the command on a pseudo-terminal (Linux only, default: false)
+  color              Prefix and panel color: name, #rrggbb or 0-255 (default: from a palette)
+  watch              Restart on file changes (include, exclude, debounce, onChange; Linux only)
+  schedule           Run periodically (cron or every, overlap: skip|queue|kill-previous, jitter, runOnStart)
 
 Examples:
   # Run a simple configuration
@@ -66,6 +107,7 @@ For more information, visit: https://github.com/sandrolain/goncurrently
 
 func main() {
 	// Handle command-line arguments
+	configPath := ""
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
 		case "--help", "-h", "help":
@@ -74,40 +116,69 @@ func main() {
 		case "--version", "-v", "version":
 			printVersion()
 			return
+		case "--config", "-c":
+			if len(os.Args) != 3 {
+				fmt.Fprintf(os.Stderr, "Usage: goncurrently %s <config.yaml>\n", os.Args[1])
+				os.Exit(1)
+			}
+			configPath = os.Args[2]
 		default:
+			if ctlCommands[os.Args[1]] {
+				os.Exit(runCtl(os.Args[1], os.Args[2:], os.Stdout, os.Stderr))
+			}
 			fmt.Fprintf(os.Stderr, "Unknown option: %s\nUse --help for usage information.\n", os.Args[1])
 			os.Exit(1)
 		}
 	}
 
-	cfg, err := loadConfig(os.Stdin)
+	var cfg Config
+	var err error
+	if configPath != "" {
+		cfg, err = loadConfigFile(configPath)
+	} else {
+		cfg, err = loadConfig(os.Stdin)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to parse config: %v\n", err)
 		os.Exit(1)
 	}
 
 	color.NoColor = cfg.NoColors
-	assignNames(cfg.Commands)
-	assignNames(cfg.SetupCommands)
-	assignNames(cfg.ShutdownCommands)
-
-	validate := validator.New()
-	if err := validate.Struct(cfg); err != nil {
+	if err := prepareConfig(&cfg); err != nil {
 		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
 		os.Exit(1)
 	}
+	// prepareConfig has validated these.
+	input, _ := newProcessInput(cfg.Commands, cfg.InputTarget)
+	theme, _ := newTUITheme(cfg.Theme)
+	statsInterval, _ := cfg.Stats.interval()
 
 	colors := defaultCommandColors()
 	panelStyles := defaultPanelStyles(cfg.Commands)
+	applyTheme(panelStyles, theme)
+	tuiOpts := newTUIOptions(cfg)
+	tuiOpts.Theme = theme
 
-	router, err := newOutputRouter(cfg.EnableTUI, cfg.Commands, panelStyles)
+	router, err := newOutputRouter(cfg.EnableTUI, cfg.Commands, panelStyles, tuiOpts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to initialize output routing: %v\n", err)
 		os.Exit(1)
 	}
 	defer router.Stop()
+	defer recoverPanic("main")
 
 	errorOutput = router.BaseWriter()
+	tui, isTUI := router.(*tuiRouter)
+	showsColors = detectColors(cfg.NoColors, isTUI)
+	if isTUI {
+		setExitCleanup(tui.Close)
+		terminals.SetSizer(tui.PanelSize)
+		lifecycleOutput = tui.LifecycleWriter()
+		tui.TrackSteps(cfg.SetupCommands, cfg.ShutdownCommands)
+	} else {
+		terminals.SetSizer(consoleTerminalSize)
+		watchTerminalResize()
+	}
 	baseLog("Initialized goncurrently | commands=%d setup=%d shutdown=%d killOthers=%t", len(cfg.Commands), len(cfg.SetupCommands), len(cfg.ShutdownCommands), cfg.KillOthers)
 
 	termination := newTerminationManager(func(sig os.Signal, immediate bool) {
@@ -123,32 +194,102 @@ func main() {
 	signals := termination.StopSignals()
 	requestStop := termination.RequestStop
 
-	runSetupSequence(cfg.SetupCommands, colors, router)
+	summary := newRunSummary()
+	if cfg.Report.enabled() {
+		summary.CaptureOutput(cfg.Report.tailLines())
+	}
+	stopStats := make(chan struct{})
+	if cfg.Stats.Enabled {
+		summary.MeasureUsage()
+		go summary.SampleUsage(statsInterval, stopStats)
+	}
+	if isTUI {
+		tui.WatchStatus(summary.Subscribe())
+		tui.BindTermination(termination)
+		if len(cfg.SetupCommands) > 0 {
+			tui.ShowSteps(true)
+		}
+	}
+	commandNames := make([]string, 0, len(cfg.Commands))
+	for _, c := range cfg.Commands {
+		commandNames = append(commandNames, c.Name)
+	}
+	controls := newProcessControl(commandNames)
+	sup := &supervisor{
+		sink:        router,
+		signals:     signals,
+		killTimeout: time.Duration(cfg.KillTimeout) * time.Millisecond,
+		killOthers:  cfg.KillOthers,
+		requestStop: requestStop,
+		summary:     summary,
+		controls:    controls,
+		input:       input,
+		colors:      colors,
+		resumable:   isTUI || cfg.ControlSocket != "",
+		config:      cfg,
+		configPath:  configPath,
+	}
+	var server *controlServer
+	if cfg.ControlSocket != "" {
+		logs := newLogHub(logHistoryLines)
+		summary.StreamLogs(logs)
+		if server, err = startControlServer(controlSocketPath(cfg.ControlSocket, configPath), sup, logs); err != nil {
+			fatalf("failed to start the control server: %v\n", err)
+		}
+	}
+	if err := runSetupSequence(cfg.SetupCommands, colors, router, summary); err != nil {
+		server.Close()
+		close(stopStats)
+		finishRouter(router, cfg.TUIKeepOpen)
+		reportSummary(cfg, summary)
+		os.Exit(1)
+	}
 	if len(cfg.SetupCommands) > 0 {
 		baseLog("Setup phase completed")
+		if isTUI {
+			tui.ShowSteps(false)
+		}
+	}
+	if isTUI {
+		tui.BindControls(controls)
+		tui.BindInput(input)
+	} else if input.Enabled() {
+		if tty, err := openConsoleInput(); err != nil {
+			baseLog("console input unavailable: %v", err)
+		} else {
+			go readConsoleInput(tty, input)
+		}
 	}
-	for i, c := range cfg.Commands {
-		router.Add()
-		go func(idx int, cc CommandConfig) {
-			defer router.Done()
-			baseLog("[%s] worker initialized", cc.Name)
-			runManagedCommand(
-				cc,
-				colors[idx%len(colors)],
-				router,
-				signals,
-				time.Duration(cfg.KillTimeout)*time.Millisecond,
-				cfg.KillOthers,
-				requestStop,
-			)
-		}(i, c)
+	sup.StartAll(cfg.Commands)
+	if configPath != "" {
+		termination.HandleReload()
+		go func() {
+			defer recoverPanic("config reload")
+			for {
+				select {
+				case <-termination.Reloads():
+					if _, err := sup.Reload("SIGHUP"); err != nil {
+						baseLog("config reload rejected: %v", err)
+					}
+				case <-signals.stop:
+					return
+				}
+			}
+		}()
 	}
 
 	router.Wait()
 
 	if len(cfg.ShutdownCommands) > 0 {
 		baseLog("Running shutdown commands...")
-		runShutdownSequence(cfg.ShutdownCommands, colors, router)
+		if isTUI {
+			tui.ShowSteps(true)
+		}
+		runShutdownSequence(cfg.ShutdownCommands, colors, router, summary)
 		baseLog("Shutdown phase completed")
 	}
+	server.Close()
+	close(stopStats)
+	finishRouter(router, cfg.TUIKeepOpen)
+	reportSummary(cfg, summary)
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+)
+
+const (
+	ruleStopAll = "stopAll"
+	ruleHook    = "hook"
+
+	streamStdout = "stdout"
+	streamStderr = "stderr"
+)
+
+// OutputRule triggers an action when a line of the command's output matches Pattern.
+type OutputRule struct {
+	Pattern string `yaml:"pattern" validate:"required"`
+	Stream  string `yaml:"stream" validate:"omitempty,oneof=stdout stderr"`
+	Action  string `yaml:"action" validate:"oneof=restart stop fail stopAll hook"`
+	Run     string `yaml:"run" validate:"required_if=Action hook"`
+}
+
+type compiledOutputRule struct {
+	OutputRule
+	pattern *regexp.Regexp
+}
+
+// watchOutputRules wraps the output writers so that lines matching onOutput
+// rules are turned into control requests for the command's worker, global
+// stop requests or hook executions. Lines are matched before any filtering,
+// while hooks receive them with the redact rules of filter applied.
+func watchOutputRules(c CommandConfig, filter *outputFilter, control chan<- controlRequest, requestStop func(), stdoutWriter, stderrWriter func(string)) (func(string), func(string)) {
+	if len(c.OnOutput) == 0 {
+		return stdoutWriter, stderrWriter
+	}
+	rules := make([]compiledOutputRule, 0, len(c.OnOutput))
+	for _, rule := range c.OnOutput {
+		rules = append(rules, compiledOutputRule{
+			OutputRule: rule,
+			pattern:    mustCompilePatternField("onOutput", rule.Pattern, c.Name),
+		})
+	}
+	wrap := func(stream string, writeLine func(string)) func(string) {
+		if writeLine == nil {
+			return nil
+		}
+		return func(line string) {
+			writeLine(line)
+			for _, rule := range rules {
+				if rule.Stream != "" && rule.Stream != stream {
+					continue
+				}
+				if rule.pattern.MatchString(line) {
+					triggerOutputRule(c.Name, rule, filter.redactSecrets(line), control, requestStop)
+				}
+			}
+		}
+	}
+	return wrap(streamStdout, stdoutWriter), wrap(streamStderr, stderrWriter)
+}
+
+func triggerOutputRule(name string, rule compiledOutputRule, line string, control chan<- controlRequest, requestStop func()) {
+	switch rule.Action {
+	case ruleStopAll:
+		baseLog("[%s] output matched %q, stopping all processes", name, rule.Pattern)
+		if requestStop != nil {
+			requestStop()
+		}
+	case ruleHook:
+		baseLog("[%s] output matched %q, running hook", name, rule.Pattern)
+		go runOutputHook(name, rule.Run, line)
+	default:
+		// When a request is already pending, extra matches are coalesced.
+		sendControl(control, controlRequest{action: rule.Action, reason: fmt.Sprintf("output matched %q", rule.Pattern)})
+	}
+}
+
+// runOutputHook executes a shell hook with the matching line exposed in the environment.
+func runOutputHook(name, script, line string) {
+	defer recoverPanic(name + " hook")
+	cmd := exec.Command("sh", "-c", script) // #nosec G204 -- hook comes from the user's config
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("GONCURRENTLY_COMMAND=%s", name),
+		fmt.Sprintf("GONCURRENTLY_LINE=%s", line),
+	)
+	out, err := cmd.CombinedOutput()
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		baseLog("[%s hook] %s", name, scanner.Text())
+	}
+	if err != nil {
+		baseLog("[%s hook] failed: %v", name, err)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchOutputRules(t *testing.T) {
+	c := CommandConfig{
+		Name: "srv",
+		OnOutput: []OutputRule{
+			{Pattern: `EADDRINUSE`, Stream: streamStderr, Action: actionRestart},
+			{Pattern: `fatal`, Action: ruleStopAll},
+		},
+	}
+	control := make(chan controlRequest, 1)
+	stopped := false
+	var forwarded []string
+	writeFunc := func(line string) {
+		forwarded = append(forwarded, line)
+	}
+
+	stdoutWriter, stderrWriter := watchOutputRules(c, nil, control, func() { stopped = true }, writeFunc, writeFunc)
+
+	stdoutWriter("Error: listen EADDRINUSE")
+	select {
+	case req := <-control:
+		t.Fatalf("stdout line must not match a stderr-only rule, got %+v", req)
+	default:
+	}
+
+	stderrWriter("Error: listen EADDRINUSE")
+	stderrWriter("Error: listen EADDRINUSE")
+	select {
+	case req := <-control:
+		if req.action != actionRestart {
+			t.Errorf("expected restart request, got %+v", req)
+		}
+	default:
+		t.Fatal("expected a control request for the stderr match")
+	}
+
+	stdoutWriter("fatal error")
+	if !stopped {
+		t.Error("expected stopAll rule to request a global stop")
+	}
+	if len(forwarded) != 4 {
+		t.Errorf("expected all lines to be forwarded, got %v", forwarded)
+	}
+}
+
+func TestOutputHookRedaction(t *testing.T) {
+	origErrorOutput := errorOutput
+	errorOutput = io.Discard
+	defer func() { errorOutput = origErrorOutput }()
+
+	path := filepath.Join(t.TempDir(), "line")
+	c := CommandConfig{
+		Name:     "srv",
+		OnOutput: []OutputRule{{Pattern: `failed`, Action: ruleHook, Run: `printf '%s' "$GONCURRENTLY_LINE" > "` + path + `"`}},
+	}
+	filter := mustCompileFilters([]FilterRule{{Pattern: `token=\S+`, Action: filterRedact}}, c.Name)
+	stdoutWriter, _ := watchOutputRules(c, filter, make(chan controlRequest, 1), nil, func(string) {}, func(string) {})
+	stdoutWriter("login token=s3cret failed")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got, err := os.ReadFile(path)
+		if err == nil && len(got) > 0 {
+			if string(got) != "login *** failed" {
+				t.Errorf("hook line = %q, want the secret redacted", got)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("hook did not run")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunOutputHook(t *testing.T) {
+	origErrorOutput := errorOutput
+	var buf bytes.Buffer
+	errorOutput = &buf
+	defer func() {
+		errorOutput = origErrorOutput
+	}()
+
+	runOutputHook("srv", `echo "$GONCURRENTLY_COMMAND got $GONCURRENTLY_LINE"`, "panic: boom")
+	if !strings.Contains(buf.String(), "[srv hook] srv got panic: boom") {
+		t.Errorf("unexpected hook output: %q", buf.String())
+	}
+}
+
+func TestRunAttemptControlRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     string
+		wantAction string
+		wantErr    bool
+	}{
+		{name: "restart", action: actionRestart, wantAction: actionRestart, wantErr: false},
+		{name: "fail", action: actionFail, wantAction: actionFail, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := CommandConfig{Name: "sleeper", Cmd: "sleep", Args: []string{"10"}}
+			control := make(chan controlRequest, 1)
+			writeFunc := func(string) {}
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				control <- controlRequest{action: tt.action, reason: "test"}
+			}()
+
+			res := runAttempt(c, "[test] ", writeFunc, writeFunc, stopSignals{}, control, nil, 100*time.Millisecond, nil)
+			if res.action != tt.wantAction {
+				t.Errorf("action = %q, want %q", res.action, tt.wantAction)
+			}
+			if tt.wantErr {
+				var exitErr *exec.ExitError
+				if !errors.As(res.err, &exitErr) {
+					t.Errorf("expected the real exit error, got %v", res.err)
+				}
+				if res.failure != "test" || !errors.As(res.failed(), &exitErr) {
+					t.Errorf("expected the fail reason wrapping the exit error, got %q, %v", res.failure, res.failed())
+				}
+			}
+		})
+	}
+}
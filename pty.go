@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+const (
+	defaultPTYCols = 80
+	defaultPTYRows = 24
+)
+
+// ptyRegistry keeps the pseudo-terminals of running commands at the size of
+// their TUI panel or of the real terminal.
+type ptyRegistry struct {
+	mu   sync.Mutex
+	size func(name string) (cols, rows int)
+	ptys map[*os.File]string
+}
+
+var terminals = &ptyRegistry{ptys: make(map[*os.File]string)}
+
+// SetSizer sets the function giving the window size of a command and applies
+// it to the running pseudo-terminals.
+func (r *ptyRegistry) SetSizer(size func(name string) (cols, rows int)) {
+	r.mu.Lock()
+	r.size = size
+	r.mu.Unlock()
+	r.resizeAll()
+}
+
+func (r *ptyRegistry) add(name string, f *os.File) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ptys[f] = name
+	r.resize(name, f)
+}
+
+func (r *ptyRegistry) remove(f *os.File) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.ptys, f)
+}
+
+func (r *ptyRegistry) resizeAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for f, name := range r.ptys {
+		r.resize(name, f)
+	}
+}
+
+func (r *ptyRegistry) resize(name string, f *os.File) {
+	cols, rows := defaultPTYCols, defaultPTYRows
+	if r.size != nil {
+		if c, h := r.size(name); c > 0 && h > 0 {
+			cols, rows = c, h
+		}
+	}
+	_ = setPTYSize(f, cols, rows) //nolint:errcheck
+}
+
+// consoleTerminalSize returns the size of the real terminal, minus the width
+// of the "[name] " prefix printed before every line.
+func consoleTerminalSize(name string) (cols, rows int) {
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		if w, h, err := term.GetSize(int(f.Fd())); err == nil {
+			return max(w-len(name)-3, 1), h
+		}
+	}
+	return 0, 0
+}
+
+// ptyInput writes to the pseudo-terminal; closing it is left to the output reader.
+type ptyInput struct {
+	*os.File
+}
+
+func (ptyInput) Close() error {
+	return nil
+}
+
+// attachPTY connects the command to a new pseudo-terminal: the child gets
+// the slave as stdin, stdout and stderr, its merged output is read from the
+// master.
+func attachPTY(p *process, name string, stdin bool) error {
+	master, slave, err := openPTY()
+	if err != nil {
+		return err
+	}
+	p.cmd.Stdin = slave
+	p.cmd.Stdout = slave
+	p.cmd.Stderr = slave
+	p.cmd.SysProcAttr = ptyProcAttr()
+	p.pty = master
+	p.ptySlave = slave
+	if stdin {
+		p.stdin = ptyInput{master}
+	}
+	terminals.add(name, master)
+	return nil
+}
+
+// releasePTY closes the parent's copy of the slave once the child started,
+// or both ends when it failed to start.
+func (p *process) releasePTY(started bool) {
+	if p.ptySlave != nil {
+		_ = p.ptySlave.Close() //nolint:errcheck
+		p.ptySlave = nil
+	}
+	if !started && p.pty != nil {
+		terminals.remove(p.pty)
+		_ = p.pty.Close() //nolint:errcheck
+	}
+}
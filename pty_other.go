@@ -0,0 +1,27 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+var errPTYUnsupported = errors.New("pseudo-terminals are only supported on Linux")
+
+func openPTY() (master, slave *os.File, err error) {
+	return nil, nil, errPTYUnsupported
+}
+
+func setPTYSize(*os.File, int, int) error {
+	return errPTYUnsupported
+}
+
+func ptyProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+func watchTerminalResize() {
+	// Nothing to resize: no pseudo-terminal can be allocated.
+}
@@ -0,0 +1,101 @@
+//go:build linux
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// runPTYCommand runs a shell script on a pty and returns its output lines.
+func runPTYCommand(t *testing.T, c CommandConfig, input *processInput, send string) []string {
+	t.Helper()
+	var mu sync.Mutex
+	var lines []string
+	write := func(line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, line)
+	}
+	done := make(chan attemptResult, 1)
+	go func() {
+		done <- runAttempt(c, "[pty] ", write, write, stopSignals{}, nil, input, time.Second, nil)
+	}()
+	if send != "" {
+		deadline := time.Now().Add(2 * time.Second)
+		for input.Send(c.Name, send) != nil {
+			if time.Now().After(deadline) {
+				t.Fatal("pty input was never attached")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if res := <-done; res.err != nil {
+		t.Fatalf("runAttempt() error = %v", res.err)
+	}
+	// runAttempt returns once the output has been read from the master.
+	terminals.mu.Lock()
+	active := len(terminals.ptys)
+	terminals.mu.Unlock()
+	if active != 0 {
+		t.Error("the terminal was not released when the attempt ended")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]string(nil), lines...)
+}
+
+func TestRunAttemptPTY(t *testing.T) {
+	c := CommandConfig{
+		Name: "tty",
+		Cmd:  "sh",
+		Args: []string{"-c", `test -t 0 && test -t 1 && test -t 2 && echo is-a-tty; stty size; echo oops >&2`},
+		PTY:  true,
+	}
+	lines := runPTYCommand(t, c, nil, "")
+	want := []string{"is-a-tty", "24 80", "oops"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("output = %q, want %q", lines, want)
+	}
+}
+
+func TestRunAttemptPTYDrainsOutput(t *testing.T) {
+	c := CommandConfig{Name: "burst", Cmd: "seq", Args: []string{"5000"}, PTY: true}
+	for i := 0; i < 5; i++ {
+		lines := runPTYCommand(t, c, nil, "")
+		if len(lines) != 5000 || lines[len(lines)-1] != "5000" {
+			t.Fatalf("run %d: %d lines read when the attempt ended, want 5000", i, len(lines))
+		}
+	}
+}
+
+func TestRunAttemptPTYSizeAndInput(t *testing.T) {
+	terminals.SetSizer(func(name string) (int, int) {
+		if name == "repl" {
+			return 100, 40
+		}
+		return 0, 0
+	})
+	t.Cleanup(func() { terminals.SetSizer(nil) })
+
+	c := CommandConfig{
+		Name:  "repl",
+		Cmd:   "sh",
+		Args:  []string{"-c", `stty size; stty -echo; read line; echo "got $line"`},
+		PTY:   true,
+		Stdin: true,
+	}
+	input, err := newProcessInput([]CommandConfig{c}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := runPTYCommand(t, c, input, "hello")
+	if len(lines) == 0 || lines[0] != "40 100" {
+		t.Errorf("expected the configured window size first, got %q", lines)
+	}
+	if got := lines[len(lines)-1]; got != "got hello" {
+		t.Errorf("expected the input to reach the process, got %q", lines)
+	}
+}
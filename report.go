@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const defaultReportTailLines = 50
+
+// ReportConfig selects the test reports written once the run completes.
+type ReportConfig struct {
+	JUnit     string `yaml:"junit"`
+	TAP       string `yaml:"tap"`
+	TailLines int    `yaml:"tailLines"`
+}
+
+func (r ReportConfig) enabled() bool {
+	return r.JUnit != "" || r.TAP != ""
+}
+
+// tailLines returns how many output lines are kept per command for report bodies.
+func (r ReportConfig) tailLines() int {
+	if r.TailLines > 0 {
+		return r.TailLines
+	}
+	return defaultReportTailLines
+}
+
+// reportOutcome classifies a command summary for test reporting.
+type reportOutcome int
+
+const (
+	outcomePassed reportOutcome = iota
+	outcomeFailed
+	outcomeError
+	outcomeSkipped
+)
+
+func classifyOutcome(row commandSummary) reportOutcome {
+	switch row.State {
+	case recordCompleted:
+		return outcomePassed
+	case recordFailed, recordStartFailed, recordTimedOut:
+		return outcomeFailed
+	case recordInterrupted:
+		return outcomeError
+	default:
+		return outcomeSkipped
+	}
+}
+
+// outcomeMessage describes why a command did not pass: the fail rule that
+// matched, or its real exit status.
+func outcomeMessage(row commandSummary) string {
+	switch {
+	case row.Failure != "":
+		return row.Failure
+	case row.State == recordStartFailed:
+		return "failed to start"
+	case row.State == recordTimedOut:
+		return "timed out"
+	case row.Signal != "":
+		return fmt.Sprintf("terminated by signal %s", row.Signal)
+	case row.ExitCode != nil:
+		return fmt.Sprintf("exit code %d", *row.ExitCode)
+	default:
+		return row.State
+	}
+}
+
+func failureOutput(row commandSummary) string {
+	if len(row.StderrTail) > 0 {
+		return strings.Join(row.StderrTail, "\n")
+	}
+	return strings.Join(row.StdoutTail, "\n")
+}
+
+// writeReports writes every configured report, returning the joined errors.
+func writeReports(cfg ReportConfig, rows []commandSummary) error {
+	var errs []error
+	if cfg.JUnit != "" {
+		errs = append(errs, writeReportFile(cfg.JUnit, rows, writeJUnitReport))
+	}
+	if cfg.TAP != "" {
+		errs = append(errs, writeReportFile(cfg.TAP, rows, writeTAPReport))
+	}
+	return errors.Join(errs...)
+}
+
+func writeReportFile(path string, rows []commandSummary, write func(io.Writer, []commandSummary) error) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600) // #nosec G304 -- path comes from the user's config
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	if err := write(f, rows); err != nil {
+		_ = f.Close() //nolint:errcheck
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// writeJUnitReport renders one testsuite per phase and one testcase per command.
+func writeJUnitReport(w io.Writer, rows []commandSummary) error {
+	doc := junitTestSuites{}
+	suiteIndex := make(map[commandPhase]int)
+	var suiteMs []int64
+	var totalMs int64
+	for _, row := range rows {
+		suiteName := fmt.Sprintf("%s.%s", basePanelName, row.Phase)
+		idx, ok := suiteIndex[row.Phase]
+		if !ok {
+			idx = len(doc.Suites)
+			suiteIndex[row.Phase] = idx
+			doc.Suites = append(doc.Suites, junitTestSuite{Name: suiteName})
+			suiteMs = append(suiteMs, 0)
+		}
+		suite := &doc.Suites[idx]
+		tc := junitTestCase{
+			Name:      row.Name,
+			ClassName: suiteName,
+			Time:      formatSeconds(row.TotalRuntimeMs),
+			SystemOut: strings.Join(row.StdoutTail, "\n"),
+			SystemErr: strings.Join(row.StderrTail, "\n"),
+		}
+		switch classifyOutcome(row) {
+		case outcomeFailed:
+			tc.Failure = &junitMessage{Message: outcomeMessage(row), Type: row.State, Body: failureOutput(row)}
+			suite.Failures++
+		case outcomeError:
+			tc.Error = &junitMessage{Message: outcomeMessage(row), Type: row.State, Body: failureOutput(row)}
+			suite.Errors++
+		case outcomeSkipped:
+			tc.Skipped = &junitMessage{Message: row.State}
+			suite.Skipped++
+		case outcomePassed:
+		}
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, tc)
+		suiteMs[idx] += row.TotalRuntimeMs
+		totalMs += row.TotalRuntimeMs
+	}
+	for i := range doc.Suites {
+		doc.Suites[i].Time = formatSeconds(suiteMs[i])
+		doc.Tests += doc.Suites[i].Tests
+		doc.Failures += doc.Suites[i].Failures
+		doc.Errors += doc.Suites[i].Errors
+		doc.Skipped += doc.Suites[i].Skipped
+	}
+	doc.Time = formatSeconds(totalMs)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeTAPReport renders a TAP version 13 stream with YAML diagnostics for failures.
+func writeTAPReport(w io.Writer, rows []commandSummary) error {
+	var b strings.Builder
+	b.WriteString("TAP version 13\n")
+	fmt.Fprintf(&b, "1..%d\n", len(rows))
+	for i, row := range rows {
+		name := fmt.Sprintf("%s:%s", row.Phase, row.Name)
+		switch classifyOutcome(row) {
+		case outcomePassed:
+			fmt.Fprintf(&b, "ok %d - %s\n", i+1, name)
+		case outcomeSkipped:
+			fmt.Fprintf(&b, "ok %d - %s # SKIP %s\n", i+1, name, row.State)
+		case outcomeFailed, outcomeError:
+			fmt.Fprintf(&b, "not ok %d - %s\n", i+1, name)
+			b.WriteString("  ---\n")
+			fmt.Fprintf(&b, "  message: %q\n", outcomeMessage(row))
+			fmt.Fprintf(&b, "  state: %q\n", row.State)
+			if row.ExitCode != nil {
+				fmt.Fprintf(&b, "  exitCode: %d\n", *row.ExitCode)
+			}
+			if row.Signal != "" {
+				fmt.Fprintf(&b, "  signal: %q\n", row.Signal)
+			}
+			fmt.Fprintf(&b, "  duration_ms: %d\n", row.TotalRuntimeMs)
+			if output := failureOutput(row); output != "" {
+				b.WriteString("  output: |\n")
+				for _, line := range strings.Split(output, "\n") {
+					fmt.Fprintf(&b, "    %s\n", line)
+				}
+			}
+			b.WriteString("  ...\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatSeconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
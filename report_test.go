@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func reportFixture() []commandSummary {
+	summary := newRunSummary()
+	summary.CaptureOutput(2)
+
+	passed := summary.Track(phaseMain, "unit")
+	passed.attemptStarted(0)
+	passed.attemptFinished(attemptResult{runtime: 1500 * time.Millisecond})
+
+	failed := summary.Track(phaseMain, "lint")
+	stdoutWriter, stderrWriter := captureOutput(failed, func(string) {}, func(string) {})
+	stdoutWriter("checking")
+	stderrWriter("first")
+	stderrWriter("second")
+	stderrWriter("third")
+	failed.attemptStarted(0)
+	failed.attemptFinished(attemptResult{err: exec.Command("sh", "-c", "exit 4").Run(), runtime: 200 * time.Millisecond})
+
+	summary.Track(phaseShutdown, "cleanup")
+	return summary.Snapshot()
+}
+
+func TestClassifyOutcome(t *testing.T) {
+	tests := []struct {
+		state string
+		want  reportOutcome
+	}{
+		{recordCompleted, outcomePassed},
+		{recordFailed, outcomeFailed},
+		{recordStartFailed, outcomeFailed},
+		{recordTimedOut, outcomeFailed},
+		{recordInterrupted, outcomeError},
+		{recordPending, outcomeSkipped},
+		{recordAborted, outcomeSkipped},
+	}
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			if got := classifyOutcome(commandSummary{State: tt.state}); got != tt.want {
+				t.Errorf("classifyOutcome(%q) = %v, want %v", tt.state, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutcomeMessage(t *testing.T) {
+	code := 0
+	tests := []struct {
+		row  commandSummary
+		want string
+	}{
+		{commandSummary{State: recordFailed, ExitCode: &code, Failure: `output matched "panic"`}, `output matched "panic"`},
+		{commandSummary{State: recordFailed, Signal: "terminated"}, "terminated by signal terminated"},
+		{commandSummary{State: recordTimedOut}, "timed out"},
+	}
+	for _, tt := range tests {
+		if got := outcomeMessage(tt.row); got != tt.want {
+			t.Errorf("outcomeMessage(%+v) = %q, want %q", tt.row, got, tt.want)
+		}
+	}
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeJUnitReport(&buf, reportFixture()); err != nil {
+		t.Fatalf("writeJUnitReport() error = %v", err)
+	}
+
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+	if doc.Tests != 3 || doc.Failures != 1 || doc.Skipped != 1 {
+		t.Errorf("unexpected totals: tests=%d failures=%d skipped=%d", doc.Tests, doc.Failures, doc.Skipped)
+	}
+	if len(doc.Suites) != 2 {
+		t.Fatalf("expected 2 suites, got %d", len(doc.Suites))
+	}
+	lint := doc.Suites[0].TestCases[1]
+	if lint.Failure == nil || lint.Failure.Message != "exit code 4" {
+		t.Fatalf("expected exit code failure, got %+v", lint.Failure)
+	}
+	if lint.Failure.Body != "second\nthird" {
+		t.Errorf("expected stderr tail in failure body, got %q", lint.Failure.Body)
+	}
+	if lint.SystemOut != "checking" {
+		t.Errorf("expected system-out to hold stdout, got %q", lint.SystemOut)
+	}
+	if doc.Suites[0].TestCases[0].Time != "1.500" {
+		t.Errorf("unexpected duration %q", doc.Suites[0].TestCases[0].Time)
+	}
+}
+
+func TestWriteTAPReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeTAPReport(&buf, reportFixture()); err != nil {
+		t.Fatalf("writeTAPReport() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"TAP version 13\n1..3\n",
+		"ok 1 - main:unit\n",
+		"not ok 2 - main:lint\n",
+		"  exitCode: 4\n",
+		"    third\n",
+		"ok 3 - shutdown:cleanup # SKIP pending\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected TAP output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteReports(t *testing.T) {
+	dir := t.TempDir()
+	cfg := ReportConfig{
+		JUnit: filepath.Join(dir, "junit.xml"),
+		TAP:   filepath.Join(dir, "report.tap"),
+	}
+	if err := writeReports(cfg, reportFixture()); err != nil {
+		t.Fatalf("writeReports() error = %v", err)
+	}
+	for _, path := range []string{cfg.JUnit, cfg.TAP} {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("expected report at %s, err=%v", path, err)
+		}
+	}
+
+	if err := writeReports(ReportConfig{JUnit: filepath.Join(dir, "missing", "junit.xml")}, nil); err == nil {
+		t.Error("expected error for unwritable report path")
+	}
+}
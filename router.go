@@ -19,13 +19,25 @@ type outputRouter interface {
 	Done()
 }
 
+// finishRouter closes the output at the end of the run; the TUI can stay open
+// until the user leaves it.
+func finishRouter(router outputRouter, keepOpen bool) {
+	if tui, ok := router.(*tuiRouter); ok {
+		tui.Finish(keepOpen)
+		return
+	}
+	router.Stop()
+}
+
 type panelAppearance struct {
-	BorderColor     tcell.Color
-	TitleColor      tcell.Color
-	BackgroundColor tcell.Color
+	BorderColor        tcell.Color
+	TitleColor         tcell.Color
+	BackgroundColor    tcell.Color
+	FocusedBorderColor tcell.Color
+	TextColor          tcell.Color
 }
 
-func newOutputRouter(enableTUI bool, commands []CommandConfig, styles map[string]panelAppearance) (outputRouter, error) {
+func newOutputRouter(enableTUI bool, commands []CommandConfig, styles map[string]panelAppearance, opts tuiOptions) (outputRouter, error) {
 	if !enableTUI {
 		return &consoleRouter{
 			wg: sync.WaitGroup{},
@@ -35,7 +47,7 @@ func newOutputRouter(enableTUI bool, commands []CommandConfig, styles map[string
 	for _, c := range commands {
 		commandNames = append(commandNames, c.Name)
 	}
-	return newTUIRouter(basePanelName, commandNames, styles)
+	return newTUIRouter(basePanelName, commandNames, styles, opts)
 }
 
 type consoleRouter struct {
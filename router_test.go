@@ -142,7 +142,7 @@ func TestNewOutputRouter_Console(t *testing.T) {
 	}
 	styles := defaultPanelStyles(commands)
 
-	router, err := newOutputRouter(false, commands, styles)
+	router, err := newOutputRouter(false, commands, styles, tuiOptions{})
 	if err != nil {
 		t.Fatalf("newOutputRouter() error = %v", err)
 	}
@@ -181,7 +181,7 @@ func TestNewOutputRouter_TUI(t *testing.T) {
 	}
 	styles := defaultPanelStyles(commands)
 
-	router, err := newOutputRouter(true, commands, styles)
+	router, err := newOutputRouter(true, commands, styles, tuiOptions{})
 	if err != nil {
 		t.Fatalf("newOutputRouter() error = %v", err)
 	}
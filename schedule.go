@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Overlap policies of a scheduled run due while the previous one is running.
+const (
+	overlapSkip         = "skip"
+	overlapQueue        = "queue"
+	overlapKillPrevious = "kill-previous"
+)
+
+// ScheduleConfig runs a main command periodically instead of once.
+type ScheduleConfig struct {
+	Cron       string `yaml:"cron"`
+	Every      string `yaml:"every"`
+	Overlap    string `yaml:"overlap" validate:"omitempty,oneof=skip queue kill-previous"`
+	Jitter     string `yaml:"jitter"`
+	RunOnStart bool   `yaml:"runOnStart"`
+}
+
+var errScheduleTiming = errors.New("set exactly one of cron and every")
+
+// check reports an invalid timing of s.
+func (s *ScheduleConfig) check(name string) error {
+	if s == nil {
+		return nil
+	}
+	if _, err := s.timing(); err != nil {
+		return fmt.Errorf("invalid schedule in command '%s': %w", name, err)
+	}
+	if _, err := time.ParseDuration(s.Jitter); s.Jitter != "" && err != nil {
+		return fmt.Errorf("invalid duration for schedule.jitter in command '%s': %w", name, err)
+	}
+	return nil
+}
+
+// timing returns the function computing the planned run following a time.
+func (s *ScheduleConfig) timing() (func(time.Time) time.Time, error) {
+	switch {
+	case (s.Cron == "") == (s.Every == ""):
+		return nil, errScheduleTiming
+	case s.Cron != "":
+		cron, err := parseCron(s.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("cron '%s': %w", s.Cron, err)
+		}
+		return cron.next, nil
+	}
+	every, err := time.ParseDuration(s.Every)
+	if err != nil {
+		return nil, fmt.Errorf("every: %w", err)
+	}
+	if every <= 0 {
+		return nil, fmt.Errorf("every must be positive, got %s", s.Every)
+	}
+	return func(t time.Time) time.Time { return t.Add(every) }, nil
+}
+
+// scheduler triggers the runs of a scheduled command at their planned times,
+// delayed by a random jitter, and applies the overlap policy to the runs due
+// while the previous one is still running.
+type scheduler struct {
+	name    string
+	next    func(time.Time) time.Time
+	jitter  time.Duration
+	overlap string
+	rec     *commandRecord
+	control chan<- controlRequest
+	// due holds at most one run waiting to start.
+	due     chan struct{}
+	running atomic.Bool
+	// halt is the global stop signal, after which no run starts.
+	halt <-chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newScheduler(c CommandConfig, rec *commandRecord, control chan<- controlRequest, stop <-chan struct{}) *scheduler {
+	next, err := c.Schedule.timing()
+	if err != nil {
+		fatalf("invalid schedule in command '%s': %v\n", c.Name, err)
+	}
+	s := &scheduler{
+		name:    c.Name,
+		next:    next,
+		jitter:  mustParseDurationField("schedule.jitter", c.Schedule.Jitter, c.Name),
+		overlap: c.Schedule.Overlap,
+		rec:     rec,
+		control: control,
+		due:     make(chan struct{}, 1),
+		halt:    stop,
+		done:    make(chan struct{}),
+	}
+	if s.overlap == "" {
+		s.overlap = overlapSkip
+	}
+	return s
+}
+
+// start plans the runs in the background until stop is called.
+func (s *scheduler) start() {
+	s.wg.Add(1)
+	go func() {
+		defer recoverPanic(s.name + " scheduler")
+		defer s.wg.Done()
+		planned := time.Now()
+		for {
+			now := time.Now()
+			if planned = s.next(planned); planned.Before(now) {
+				// Runs missed while the system was suspended are not caught up.
+				planned = s.next(now)
+			}
+			at := planned
+			if s.jitter > 0 {
+				at = at.Add(rand.N(s.jitter)) // #nosec G404 -- jitter needs no cryptographic randomness
+			}
+			s.rec.runPlanned(at)
+			timer := time.NewTimer(time.Until(at))
+			select {
+			case <-s.done:
+				timer.Stop()
+				return
+			case <-s.halt:
+				timer.Stop()
+				return
+			case <-timer.C:
+				s.trigger()
+			}
+		}
+	}()
+}
+
+func (s *scheduler) stop() {
+	close(s.done)
+	s.wg.Wait()
+}
+
+// trigger starts a run, or applies the overlap policy when one is running.
+func (s *scheduler) trigger() {
+	if !s.running.Load() {
+		s.enqueue()
+		return
+	}
+	switch s.overlap {
+	case overlapQueue:
+		if s.enqueue() {
+			baseLog("[%s] scheduled run queued after the running one", s.name)
+		} else {
+			s.rec.runSkipped()
+			baseLog("[%s] scheduled run skipped, a run is already queued", s.name)
+		}
+	case overlapKillPrevious:
+		if !sendControl(s.control, controlRequest{action: actionRestart, reason: "next scheduled run is due"}) {
+			s.rec.runSkipped()
+			baseLog("[%s] scheduled run skipped, a request is already pending", s.name)
+		}
+	default:
+		s.rec.runSkipped()
+		baseLog("[%s] scheduled run skipped, the previous one is still running", s.name)
+	}
+}
+
+func (s *scheduler) enqueue() bool {
+	select {
+	case s.due <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// runScheduledCommand supervises a scheduled command: every run starts a new
+// process, retried according to restartTries when it fails. A stop request
+// pauses the schedule until the command is started again, and the worker only
+// ends with the run or on a config reload.
+func runScheduledCommand(c CommandConfig, identifier string, stdoutWriter, stderrWriter func(string), signals stopSignals, killTimeout time.Duration, rec *commandRecord, control chan controlRequest, input *processInput) {
+	s := newScheduler(c, rec, control, signals.stop)
+	s.start()
+	defer s.stop()
+	if c.Schedule.RunOnStart {
+		s.enqueue()
+	}
+	paused := false
+	run := 0
+	for {
+		reason := "scheduled"
+		select {
+		case <-signals.stop:
+			baseLog("[%s] interrupted", c.Name)
+			return
+		case <-s.due:
+			if paused {
+				continue
+			}
+		case req := <-control:
+			switch req.action {
+			case actionStart, actionRestart:
+				paused = false
+				reason = fmt.Sprintf("%s requested: %s", req.action, req.reason)
+			case actionStop, actionKill:
+				if !paused {
+					paused = true
+					baseLog("[%s] schedule paused on request: %s", c.Name, req.reason)
+				}
+				continue
+			case actionRemove:
+				baseLog("[%s] worker ended for a config reload", c.Name)
+				return
+			default:
+				continue
+			}
+		}
+		// A restart request ends the current run and starts the next one.
+		for {
+			run++
+			res, ended := s.execute(c, run, reason, identifier, stdoutWriter, stderrWriter, signals, killTimeout, control, input)
+			if ended {
+				return
+			}
+			if res.action != actionRestart {
+				if res.action == actionStop || res.action == actionKill {
+					paused = true
+					baseLog("[%s] schedule paused on request", c.Name)
+				}
+				break
+			}
+			reason = "restart requested"
+		}
+	}
+}
+
+// execute performs a run and its retries. It reports true when the worker
+// must end.
+func (s *scheduler) execute(c CommandConfig, run int, reason, identifier string, stdoutWriter, stderrWriter func(string), signals stopSignals, killTimeout time.Duration, control chan controlRequest, input *processInput) (attemptResult, bool) {
+	s.running.Store(true)
+	defer s.running.Store(false)
+	s.rec.runStarted()
+	baseLog("[%s] run %d started (%s)", c.Name, run, reason)
+	logCommandLine(stdoutWriter, nil, identifier, fmt.Sprintf("run %d (%s)", run, reason))
+	triesLeft := c.RestartTries
+	attempt := 1
+	for {
+		res := runAttempt(c, identifier, stdoutWriter, stderrWriter, signals, control, input, killTimeout, s.rec)
+		switch {
+		case res.interrupted:
+			baseLog("[%s] interrupted", c.Name)
+			return res, true
+		case res.action == actionRemove:
+			baseLog("[%s] worker ended for a config reload", c.Name)
+			return res, true
+		case res.action != "" && res.action != actionFail:
+			return res, false
+		}
+		logCommandOutcome(c.Name, res.failed(), res.timedOut)
+		if !shouldRestart(res.failed(), res.timedOut, &triesLeft, c.RestartTries) {
+			return res, false
+		}
+		attempt++
+		logRestartSchedule(c.Name, attempt, c.RestartTries, triesLeft)
+		s.rec.restartScheduled(attempt, c.RestartTries+1)
+		if waitRestartDelay(c, signals.stop) {
+			baseLog("[%s] restart aborted due to stop signal", c.Name)
+			s.rec.markAborted()
+			return res, true
+		}
+	}
+}
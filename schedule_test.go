@@ -0,0 +1,155 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleConfigCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *ScheduleConfig
+		wantErr  string
+	}{
+		{"none", nil, ""},
+		{"every", &ScheduleConfig{Every: "5m", Jitter: "10s"}, ""},
+		{"cron", &ScheduleConfig{Cron: "*/5 * * * *", Overlap: overlapQueue}, ""},
+		{"no timing", &ScheduleConfig{RunOnStart: true}, "exactly one"},
+		{"both timings", &ScheduleConfig{Cron: "@daily", Every: "1h"}, "exactly one"},
+		{"invalid cron", &ScheduleConfig{Cron: "* * *"}, "cron '* * *'"},
+		{"invalid every", &ScheduleConfig{Every: "often"}, "every"},
+		{"negative every", &ScheduleConfig{Every: "-1m"}, "positive"},
+		{"invalid jitter", &ScheduleConfig{Every: "1m", Jitter: "x"}, "schedule.jitter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schedule.check("job")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("check() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("check() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchedulerTrigger(t *testing.T) {
+	origErrorOutput := errorOutput
+	errorOutput = io.Discard
+	defer func() { errorOutput = origErrorOutput }()
+
+	tests := []struct {
+		overlap string
+		due     int
+		request string
+		skipped int
+	}{
+		{overlapSkip, 0, "", 2},
+		{overlapQueue, 1, "", 1},
+		{overlapKillPrevious, 0, actionRestart, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.overlap, func(t *testing.T) {
+			rec := newRunSummary().Track(phaseMain, "job")
+			control := make(chan controlRequest, 1)
+			s := newScheduler(CommandConfig{Name: "job", Schedule: &ScheduleConfig{Every: "1m", Overlap: tt.overlap}}, rec, control, nil)
+
+			s.trigger()
+			if len(s.due) != 1 {
+				t.Fatal("expected an idle command to run")
+			}
+			<-s.due
+			s.running.Store(true)
+			s.trigger()
+			s.trigger()
+			if len(s.due) != tt.due {
+				t.Errorf("queued runs = %d, want %d", len(s.due), tt.due)
+			}
+			request := ""
+			select {
+			case req := <-control:
+				request = req.action
+			default:
+			}
+			if request != tt.request {
+				t.Errorf("request = %q, want %q", request, tt.request)
+			}
+			if got := rec.snapshot().SkippedRuns; got != tt.skipped {
+				t.Errorf("skipped runs = %d, want %d", got, tt.skipped)
+			}
+		})
+	}
+}
+
+func waitForRecord(t *testing.T, rec *commandRecord, check func(statusEvent) bool) statusEvent {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		ev := rec.status()
+		if check(ev) {
+			return ev
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected status %+v", ev)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRunScheduledCommand(t *testing.T) {
+	origErrorOutput := errorOutput
+	errorOutput = io.Discard
+	defer func() { errorOutput = origErrorOutput }()
+
+	summary := newRunSummary()
+	rec := summary.Track(phaseMain, "job")
+	control := make(chan controlRequest, 1)
+	stop := make(chan struct{})
+	sink := &recordingRouter{}
+	c := CommandConfig{Name: "job", Cmd: "sh", Args: []string{"-c", "echo tick"}, Schedule: &ScheduleConfig{Every: "30ms", RunOnStart: true}}
+	done := make(chan struct{})
+	go func() {
+		runManagedCommand(c, nil, sink, stopSignals{stop: stop}, time.Second, true, nil, rec, control, nil, false)
+		close(done)
+	}()
+
+	ev := waitForRecord(t, rec, func(ev statusEvent) bool { return ev.Runs >= 3 && ev.Label == statusScheduled })
+	if ev.Attempt < 3 || ev.Restarts != 0 {
+		t.Errorf("runs must count as attempts without restarts: %+v", ev)
+	}
+	if ev.NextRun.IsZero() {
+		t.Error("expected the next run to be planned")
+	}
+
+	control <- controlRequest{action: actionStop, reason: "test"}
+	paused := waitForRecord(t, rec, func(ev statusEvent) bool { return !ev.Running }).Runs
+	time.Sleep(100 * time.Millisecond)
+	if runs := rec.status().Runs; runs > paused+1 {
+		t.Errorf("runs went on while paused: %d, then %d", paused, runs)
+	}
+
+	runs := rec.status().Runs
+	control <- controlRequest{action: actionStart, reason: "test"}
+	waitForRecord(t, rec, func(ev statusEvent) bool { return ev.Runs > runs })
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("scheduled worker did not end on stop")
+	}
+	if got := summary.Snapshot()[0]; got.Runs < 4 || got.KilledByOthers {
+		t.Errorf("summary = %+v", got)
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if !strings.Contains(strings.Join(sink.lines, "\n"), "[job] run 2 (scheduled)\n[job] tick") {
+		t.Errorf("runs are not marked in the output: %q", sink.lines)
+	}
+}
@@ -2,44 +2,48 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/fatih/color"
 )
 
-func runSetupSequence(cmds []CommandConfig, colors []*color.Color, sink outputRouter) {
+// runSetupSequence executes setup commands sequentially and reports the first
+// command that still fails after its retries.
+func runSetupSequence(cmds []CommandConfig, colors []*color.Color, sink outputRouter, summary *runSummary) error {
+	records := make([]*commandRecord, len(cmds))
 	for i, c := range cmds {
-		col := colors[i%len(colors)]
-		identifier := fmt.Sprintf("[setup:%s] ", c.Name)
-		stdoutWriter := sink.LineWriter(basePanelName, col, identifier)
-		stderrWriter := sink.LineWriter(basePanelName, col, fmt.Sprintf("[setup:%s stderr] ", c.Name))
+		records[i] = summary.Track(phaseSetup, c.Name)
+	}
+	for i, c := range cmds {
+		identifier, stdoutWriter, stderrWriter := sequenceWriters(sink, phaseSetup, c.Name, commandColor(c, colors[i%len(colors)]))
 		if d := mustParseDurationField("startAfter", c.StartAfter, c.Name); d > 0 {
 			time.Sleep(d)
 		}
 		baseLog("[setup:%s] starting", c.Name)
-		if !runSetupWithRetries(c, identifier, stdoutWriter, stderrWriter) {
+		if !runSetupWithRetries(c, identifier, stdoutWriter, stderrWriter, records[i]) {
 			color.New(color.FgRed, color.Bold).Fprintf(errorOutput, "Setup command '%s' failed after retries\n", c.Name) //nolint:errcheck
-			os.Exit(1)
+			return fmt.Errorf("setup command '%s' failed", c.Name)
 		}
 		baseLog("[setup:%s] completed", c.Name)
 	}
+	return nil
 }
 
 // runShutdownSequence executes shutdown commands sequentially.
 // Unlike setup commands, shutdown commands do not terminate the process on failure
 // but log errors and continue with the remaining commands.
-func runShutdownSequence(cmds []CommandConfig, colors []*color.Color, sink outputRouter) {
+func runShutdownSequence(cmds []CommandConfig, colors []*color.Color, sink outputRouter, summary *runSummary) {
+	records := make([]*commandRecord, len(cmds))
+	for i, c := range cmds {
+		records[i] = summary.Track(phaseShutdown, c.Name)
+	}
 	for i, c := range cmds {
-		col := colors[i%len(colors)]
-		identifier := fmt.Sprintf("[shutdown:%s] ", c.Name)
-		stdoutWriter := sink.LineWriter(basePanelName, col, identifier)
-		stderrWriter := sink.LineWriter(basePanelName, col, fmt.Sprintf("[shutdown:%s stderr] ", c.Name))
+		identifier, stdoutWriter, stderrWriter := sequenceWriters(sink, phaseShutdown, c.Name, commandColor(c, colors[i%len(colors)]))
 		if d := mustParseDurationField("startAfter", c.StartAfter, c.Name); d > 0 {
 			time.Sleep(d)
 		}
 		baseLog("[shutdown:%s] starting", c.Name)
-		if !runSetupWithRetries(c, identifier, stdoutWriter, stderrWriter) {
+		if !runSetupWithRetries(c, identifier, stdoutWriter, stderrWriter, records[i]) {
 			color.New(color.FgYellow, color.Bold).Fprintf(errorOutput, "Shutdown command '%s' failed after retries\n", c.Name) //nolint:errcheck
 			// Continue with remaining shutdown commands instead of exiting
 			continue
@@ -47,3 +51,17 @@ func runShutdownSequence(cmds []CommandConfig, colors []*color.Color, sink outpu
 		baseLog("[shutdown:%s] completed", c.Name)
 	}
 }
+
+// sequenceWriters returns the identifier and the output writers of a setup or
+// shutdown command, whose output goes to the base panel. The TUI also keeps it
+// for the steps view.
+func sequenceWriters(sink outputRouter, phase commandPhase, name string, col *color.Color) (string, func(string), func(string)) {
+	identifier := fmt.Sprintf("[%s:%s] ", phase, name)
+	stdoutWriter := sink.LineWriter(basePanelName, col, identifier)
+	stderrWriter := sink.LineWriter(basePanelName, col, fmt.Sprintf("[%s:%s stderr] ", phase, name))
+	if tui, ok := sink.(*tuiRouter); ok {
+		stdoutWriter = tui.StepWriter(phase, name, streamStdout, stdoutWriter)
+		stderrWriter = tui.StepWriter(phase, name, streamStderr, stderrWriter)
+	}
+	return identifier, stdoutWriter, stderrWriter
+}
@@ -74,7 +74,9 @@ func TestRunSetupSequence(t *testing.T) {
 
 			// This will call os.Exit(1) on failure, so we can't easily test failure cases
 			if !tt.wantErr {
-				runSetupSequence(tt.commands, colors, router)
+				if err := runSetupSequence(tt.commands, colors, router, nil); err != nil {
+					t.Errorf("runSetupSequence() error = %v", err)
+				}
 			}
 		})
 	}
@@ -157,7 +159,7 @@ func TestRunShutdownSequence(t *testing.T) {
 			router := &consoleRouter{}
 
 			// runShutdownSequence should not panic or exit on failures
-			runShutdownSequence(tt.commands, colors, router)
+			runShutdownSequence(tt.commands, colors, router, nil)
 		})
 	}
 }
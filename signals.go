@@ -19,6 +19,10 @@ type terminationManager struct {
 	immediateOnce sync.Once
 	shutdownCh    chan struct{}
 	shutdownOnce  sync.Once
+	signals       chan os.Signal
+	force         chan struct{}
+	forceOnce     sync.Once
+	reloads       chan struct{}
 	done          chan struct{}
 	handler       func(os.Signal, bool)
 }
@@ -28,6 +32,9 @@ func newTerminationManager(handler func(os.Signal, bool)) *terminationManager {
 		stopCh:      make(chan struct{}),
 		immediateCh: make(chan struct{}),
 		shutdownCh:  make(chan struct{}),
+		signals:     make(chan os.Signal, 1),
+		force:       make(chan struct{}),
+		reloads:     make(chan struct{}, 1),
 		done:        make(chan struct{}),
 		handler:     handler,
 	}
@@ -36,9 +43,10 @@ func newTerminationManager(handler func(os.Signal, bool)) *terminationManager {
 }
 
 func (tm *terminationManager) listen() {
+	defer recoverPanic("signal handler")
 	defer close(tm.done)
 
-	sigCh := make(chan os.Signal, 1)
+	sigCh := tm.signals
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	defer signal.Stop(sigCh)
 
@@ -49,6 +57,10 @@ func (tm *terminationManager) listen() {
 			if sig == nil {
 				continue
 			}
+			if sig == syscall.SIGHUP {
+				tm.Reload()
+				continue
+			}
 			if !firstHandled {
 				if tm.handler != nil {
 					tm.handler(sig, false)
@@ -62,12 +74,54 @@ func (tm *terminationManager) listen() {
 				tm.triggerImmediate()
 				return
 			}
+		case <-tm.force:
+			if tm.handler != nil {
+				tm.handler(os.Interrupt, true)
+			}
+			tm.triggerImmediate()
+			return
 		case <-tm.shutdownCh:
 			return
 		}
 	}
 }
 
+// Interrupt handles an interrupt that did not arrive as a signal, such as
+// Ctrl+C pressed in the TUI, like SIGINT.
+func (tm *terminationManager) Interrupt() {
+	select {
+	case tm.signals <- os.Interrupt:
+	default:
+	}
+}
+
+// HandleReload makes SIGHUP request a reload instead of terminating
+// goncurrently.
+func (tm *terminationManager) HandleReload() {
+	signal.Notify(tm.signals, syscall.SIGHUP)
+}
+
+// Reload requests a reload of the config, like SIGHUP. Requests arriving
+// while one is pending are merged into it.
+func (tm *terminationManager) Reload() {
+	select {
+	case tm.reloads <- struct{}{}:
+	default:
+	}
+}
+
+// Reloads receives the reload requests.
+func (tm *terminationManager) Reloads() <-chan struct{} {
+	return tm.reloads
+}
+
+// Terminate forces the termination at once, like a second interrupt.
+func (tm *terminationManager) Terminate() {
+	tm.forceOnce.Do(func() {
+		close(tm.force)
+	})
+}
+
 func (tm *terminationManager) triggerImmediate() {
 	tm.immediateOnce.Do(func() {
 		close(tm.immediateCh)
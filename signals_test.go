@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -186,6 +187,37 @@ func TestTerminationManager_TriggerImmediate(t *testing.T) {
 	tm.triggerImmediate()
 }
 
+func TestTerminationManager_Interrupt(t *testing.T) {
+	immediate := make(chan bool, 2)
+	tm := newTerminationManager(func(_ os.Signal, now bool) {
+		immediate <- now
+	})
+	defer tm.Shutdown()
+	signals := tm.StopSignals()
+
+	tm.Interrupt()
+	select {
+	case <-signals.stop:
+		// Expected
+	case <-time.After(time.Second):
+		t.Fatal("stop channel should be closed after the first interrupt")
+	}
+	if <-immediate {
+		t.Error("first interrupt should stop gracefully")
+	}
+
+	tm.Interrupt()
+	select {
+	case <-signals.immediate:
+		// Expected
+	case <-time.After(time.Second):
+		t.Fatal("immediate channel should be closed after the second interrupt")
+	}
+	if !<-immediate {
+		t.Error("second interrupt should force termination")
+	}
+}
+
 func TestTerminationManager_WithNilHandler(t *testing.T) {
 	tm := newTerminationManager(nil)
 	defer tm.Shutdown()
@@ -202,3 +234,59 @@ func TestTerminationManager_WithNilHandler(t *testing.T) {
 		t.Error("stop channel should be closed")
 	}
 }
+
+func TestTerminationManager_Terminate(t *testing.T) {
+	immediate := make(chan bool, 1)
+	tm := newTerminationManager(func(_ os.Signal, now bool) {
+		immediate <- now
+	})
+	defer tm.Shutdown()
+	signals := tm.StopSignals()
+
+	tm.Terminate()
+	select {
+	case <-signals.immediate:
+		// Expected
+	case <-time.After(time.Second):
+		t.Fatal("immediate channel should be closed at once")
+	}
+	select {
+	case <-signals.stop:
+		// Expected
+	default:
+		t.Error("stop channel should be closed as well")
+	}
+	if !<-immediate {
+		t.Error("terminate should force termination")
+	}
+}
+
+func TestTerminationManager_Reload(t *testing.T) {
+	tm := newTerminationManager(nil)
+	defer tm.Shutdown()
+
+	tm.Reload()
+	tm.Reload()
+	select {
+	case <-tm.Reloads():
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("expected a reload request")
+	}
+	select {
+	case <-tm.Reloads():
+		t.Error("expected pending reload requests to be merged")
+	default:
+	}
+
+	tm.signals <- syscall.SIGHUP
+	select {
+	case <-tm.Reloads():
+	case <-time.After(time.Second):
+		t.Fatal("expected SIGHUP to request a reload")
+	}
+	select {
+	case <-tm.StopSignals().stop:
+		t.Error("SIGHUP must not stop the run")
+	default:
+	}
+}
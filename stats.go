@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStatsInterval = 2 * time.Second
+	// clockTicksPerSecond is USER_HZ, the unit of the CPU times in /proc,
+	// which is 100 on every Linux architecture.
+	clockTicksPerSecond = 100
+)
+
+// StatsConfig enables the sampling of the CPU and memory used by commands.
+type StatsConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Interval string `yaml:"interval"`
+	Pane     bool   `yaml:"pane"`
+}
+
+// interval returns the sampling interval, 2s by default.
+func (c StatsConfig) interval() (time.Duration, error) {
+	if c.Interval == "" {
+		return defaultStatsInterval, nil
+	}
+	d, err := time.ParseDuration(c.Interval)
+	if err != nil {
+		return 0, fmt.Errorf("stats.interval: %w", err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("stats.interval: %s is not positive", c.Interval)
+	}
+	return d, nil
+}
+
+// procInfo is a process read from the process table.
+type procInfo struct {
+	ppid int
+	// ticks is the CPU time in user and system mode, in clock ticks.
+	ticks   uint64
+	threads int
+	// rss is the resident memory in bytes.
+	rss uint64
+}
+
+// processUsage is the resource usage of a command and its descendants at the
+// latest sample. Threads is 0 until the command has been sampled.
+type processUsage struct {
+	CPUPercent float64
+	RSS        uint64
+	Threads    int
+	SampledAt  time.Time
+}
+
+// SampleUsage samples the resource usage of the running commands every
+// interval until stop is closed.
+func (s *runSummary) SampleUsage(interval time.Duration, stop <-chan struct{}) {
+	if s == nil {
+		return
+	}
+	defer recoverPanic("stats sampler")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			procs, err := readProcesses()
+			if err != nil {
+				baseLog("process stats unavailable: %v", err)
+				return
+			}
+			s.recordUsage(procs, now, now.Sub(last))
+			last = now
+		}
+	}
+}
+
+// recordUsage updates every running command with the processes sampled
+// elapsed after the previous sample.
+func (s *runSummary) recordUsage(procs map[int]procInfo, now time.Time, elapsed time.Duration) {
+	children := make(map[int][]int, len(procs))
+	for pid, info := range procs {
+		children[info.ppid] = append(children[info.ppid], pid)
+	}
+	s.mu.Lock()
+	records := append([]*commandRecord(nil), s.records...)
+	s.mu.Unlock()
+	for _, rec := range records {
+		rec.sampleUsage(procs, children, now, elapsed)
+	}
+}
+
+// processTree returns root and its descendants.
+func processTree(root int, children map[int][]int) []int {
+	tree := []int{root}
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i]]...)
+	}
+	return tree
+}
+
+// sampleUsage sums the usage of the process of the record and of its
+// descendants. The CPU percentage counts the CPU time of every process since
+// its previous sample or its start.
+func (r *commandRecord) sampleUsage(procs map[int]procInfo, children map[int][]int, now time.Time, elapsed time.Duration) {
+	r.mu.Lock()
+	if !r.running || r.pid <= 0 {
+		r.mu.Unlock()
+		return
+	}
+	if _, ok := procs[r.pid]; !ok {
+		r.mu.Unlock()
+		return
+	}
+	usage := processUsage{SampledAt: now}
+	var delta uint64
+	ticks := make(map[int]uint64)
+	for _, pid := range processTree(r.pid, children) {
+		info := procs[pid]
+		usage.RSS += info.rss
+		usage.Threads += info.threads
+		if prev, ok := r.cpuTicks[pid]; ok && info.ticks >= prev {
+			delta += info.ticks - prev
+		} else {
+			delta += info.ticks
+		}
+		ticks[pid] = info.ticks
+	}
+	if elapsed > 0 {
+		usage.CPUPercent = float64(delta) / clockTicksPerSecond / elapsed.Seconds() * 100
+	}
+	r.version++
+	r.cpuTicks = ticks
+	r.usage = usage
+	r.peakRSS = max(r.peakRSS, usage.RSS)
+	r.mu.Unlock()
+	r.publish()
+}
+
+// sparkBlocks draws the values of a sparkline from the lowest to the highest.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values scaled to limit, or to their maximum when higher.
+func sparkline(values []float64, limit float64) string {
+	for _, v := range values {
+		limit = math.Max(limit, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		index := 0
+		if limit > 0 {
+			index = int(math.Round(v / limit * float64(len(sparkBlocks)-1)))
+		}
+		b.WriteRune(sparkBlocks[min(max(index, 0), len(sparkBlocks)-1)])
+	}
+	return b.String()
+}
+
+// formatBytes renders a size with a binary unit, e.g. 12.5MiB.
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", value, "KMGTP"[exp])
+}
+
+// usageLabel renders the usage of a command in its panel title.
+func usageLabel(u processUsage) string {
+	return fmt.Sprintf("%.1f%% %s %dt", u.CPUPercent, formatBytes(u.RSS), u.Threads)
+}
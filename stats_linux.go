@@ -0,0 +1,98 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readProcesses reads the process table from /proc.
+func readProcesses() (map[int]procInfo, error) {
+	return readProcFS("/proc")
+}
+
+// readProcFS reads every process of a proc file system. Processes exiting
+// while it is read are skipped.
+func readProcFS(root string) (map[int]procInfo, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", root, err)
+	}
+	procs := make(map[int]procInfo, len(entries))
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		stat, err := os.ReadFile(filepath.Join(dir, "stat")) // #nosec G304 -- files of the proc file system
+		if err != nil {
+			continue
+		}
+		info, err := parseProcStat(stat)
+		if err != nil {
+			continue
+		}
+		if status, err := os.ReadFile(filepath.Join(dir, "status")); err == nil { // #nosec G304 -- files of the proc file system
+			info.rss = parseProcRSS(status)
+		}
+		procs[pid] = info
+	}
+	return procs, nil
+}
+
+// parseProcStat reads the parent, the CPU time and the threads of a process
+// from /proc/<pid>/stat. The fields follow the command name, in parentheses,
+// which may itself contain spaces and parentheses.
+func parseProcStat(data []byte) (procInfo, error) {
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return procInfo{}, fmt.Errorf("malformed stat")
+	}
+	// fields[0] is the third field of the file, the state.
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 18 {
+		return procInfo{}, fmt.Errorf("malformed stat: %d fields", len(fields))
+	}
+	var values [4]uint64
+	for i, index := range []int{1, 11, 12, 17} {
+		v, err := strconv.ParseUint(fields[index], 10, 64)
+		if err != nil {
+			return procInfo{}, fmt.Errorf("malformed stat: %w", err)
+		}
+		values[i] = v
+	}
+	return procInfo{
+		ppid:    int(values[0]), // #nosec G115 -- pids fit in int
+		ticks:   values[1] + values[2],
+		threads: int(values[3]), // #nosec G115 -- thread counts fit in int
+	}, nil
+}
+
+// parseProcRSS reads the resident memory from /proc/<pid>/status, 0 for
+// processes without memory such as zombies.
+func parseProcRSS(data []byte) uint64 {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "VmRSS:")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			return 0
+		}
+		kb, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return 0
+		}
+		return kb * 1024
+	}
+	return 0
+}
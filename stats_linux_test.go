@@ -0,0 +1,68 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseProcStat(t *testing.T) {
+	stat := "1234 (my (odd) cmd) S 42 1234 1234 0 -1 4194560 100 0 0 0 70 30 5 5 20 0 6 0 300 1000 200 18446744073709551615"
+	info, err := parseProcStat([]byte(stat))
+	if err != nil {
+		t.Fatalf("parseProcStat() error = %v", err)
+	}
+	if info.ppid != 42 || info.ticks != 100 || info.threads != 6 {
+		t.Errorf("parseProcStat() = %+v, want ppid 42, 100 ticks and 6 threads", info)
+	}
+	if _, err := parseProcStat([]byte("1234 (cmd) S 42")); err == nil {
+		t.Error("expected an error for a truncated stat")
+	}
+}
+
+func TestParseProcRSS(t *testing.T) {
+	status := "Name:\tapi\nVmPeak:\t  9000 kB\nVmRSS:\t  2048 kB\nThreads:\t6\n"
+	if got := parseProcRSS([]byte(status)); got != 2048*1024 {
+		t.Errorf("parseProcRSS() = %d, want 2MiB", got)
+	}
+	if got := parseProcRSS([]byte("Name:\tzombie\n")); got != 0 {
+		t.Errorf("parseProcRSS() of a zombie = %d, want 0", got)
+	}
+}
+
+func TestReadProcFS(t *testing.T) {
+	root := t.TempDir()
+	write := func(path, data string) {
+		t.Helper()
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("7/stat", "7 (api) S 1 7 7 0 -1 0 0 0 0 0 10 5 0 0 20 0 3 0 1 1 1")
+	write("7/status", "VmRSS:\t100 kB\n")
+	write("8/stat", "garbage")
+	write("self/stat", "7 (api) S 1 7 7 0 -1 0 0 0 0 0 10 5 0 0 20 0 3 0 1 1 1")
+
+	procs, err := readProcFS(root)
+	if err != nil {
+		t.Fatalf("readProcFS() error = %v", err)
+	}
+	want := procInfo{ppid: 1, ticks: 15, threads: 3, rss: 100 * 1024}
+	if len(procs) != 1 || procs[7] != want {
+		t.Errorf("readProcFS() = %+v, want only pid 7 = %+v", procs, want)
+	}
+
+	procs, err = readProcesses()
+	if err != nil {
+		t.Fatalf("readProcesses() error = %v", err)
+	}
+	if self, ok := procs[os.Getpid()]; !ok || self.threads == 0 || self.rss == 0 {
+		t.Errorf("readProcesses() of the test process = %+v, %t", self, ok)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+var errStatsUnsupported = errors.New("process stats are only available on Linux")
+
+func readProcesses() (map[int]procInfo, error) {
+	return nil, errStatsUnsupported
+}
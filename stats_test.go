@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsConfigInterval(t *testing.T) {
+	tests := []struct {
+		interval string
+		want     time.Duration
+		wantErr  bool
+	}{
+		{interval: "", want: defaultStatsInterval},
+		{interval: "500ms", want: 500 * time.Millisecond},
+		{interval: "0s", wantErr: true},
+		{interval: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := StatsConfig{Interval: tt.interval}.interval()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("interval(%q) = %v, %v, want %v (error %t)", tt.interval, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRecordUsage(t *testing.T) {
+	summary := newRunSummary()
+	summary.MeasureUsage()
+	rec := summary.Track(phaseMain, "api")
+	idle := summary.Track(phaseMain, "idle")
+	rec.attemptStarted(10)
+	now := time.Now()
+
+	// The command, its child and grandchild, and an unrelated process.
+	summary.recordUsage(map[int]procInfo{
+		10: {ppid: 1, ticks: 50, threads: 2, rss: 10 << 20},
+		11: {ppid: 10, ticks: 30, threads: 1, rss: 5 << 20},
+		12: {ppid: 11, ticks: 20, threads: 1, rss: 1 << 20},
+		99: {ppid: 1, ticks: 500, threads: 8, rss: 100 << 20},
+	}, now, time.Second)
+	ev := rec.status()
+	if ev.Usage.Threads != 4 || ev.Usage.RSS != 16<<20 || ev.Usage.CPUPercent != 100 {
+		t.Errorf("first sample = %+v, want 4 threads, 16MiB and 100%%", ev.Usage)
+	}
+
+	// The grandchild exited; the others used 50 more ticks in 2s.
+	summary.recordUsage(map[int]procInfo{
+		10: {ppid: 1, ticks: 90, threads: 2, rss: 8 << 20},
+		11: {ppid: 10, ticks: 40, threads: 1, rss: 5 << 20},
+	}, now.Add(2*time.Second), 2*time.Second)
+	ev = rec.status()
+	if ev.Usage.Threads != 3 || ev.Usage.RSS != 13<<20 || ev.Usage.CPUPercent != 25 {
+		t.Errorf("second sample = %+v, want 3 threads, 13MiB and 25%%", ev.Usage)
+	}
+
+	// The CPU time comes from the exit status, not from the samples.
+	rec.attemptFinished(attemptResult{runtime: time.Second, cpuTime: 1700 * time.Millisecond})
+	if rec.status().Usage != (processUsage{}) {
+		t.Error("a finished command should have no usage")
+	}
+	row := rec.snapshot()
+	if row.CPUTimeMs == nil || *row.CPUTimeMs != 1700 {
+		t.Errorf("CPU time = %v, want 1700ms", row.CPUTimeMs)
+	}
+	if row.PeakRSSBytes == nil || *row.PeakRSSBytes != 16<<20 {
+		t.Errorf("peak RSS = %v, want 16MiB", row.PeakRSSBytes)
+	}
+	if idle.snapshot().PeakRSSBytes != nil {
+		t.Error("a command never sampled should have no peak memory")
+	}
+	if newRunSummary().Track(phaseMain, "plain").snapshot().CPUTimeMs != nil {
+		t.Error("CPU time should only be reported with stats enabled")
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		values []float64
+		limit  float64
+		want   string
+	}{
+		{values: []float64{0, 50, 100}, limit: 100, want: "▁▅█"},
+		{values: []float64{0, 200}, limit: 100, want: "▁█"},
+		{values: []float64{0, 0}, limit: 0, want: "▁▁"},
+		{values: nil, limit: 100, want: ""},
+	}
+	for _, tt := range tests {
+		if got := sparkline(tt.values, tt.limit); got != tt.want {
+			t.Errorf("sparkline(%v, %v) = %q, want %q", tt.values, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{n: 512, want: "512B"},
+		{n: 1536, want: "1.5KiB"},
+		{n: 64 << 20, want: "64.0MiB"},
+		{n: 3 << 30, want: "3.0GiB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
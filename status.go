@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const statusEventBuffer = 256
+
+// Labels describing the live state of a command.
+const (
+	statusWaiting     = "waiting"
+	statusStarting    = "starting"
+	statusRunning     = "running"
+	statusReady       = "ready"
+	statusRestarting  = "restarting"
+	statusTimedOut    = "timed out"
+	statusStopped     = "stopped"
+	statusInterrupted = "interrupted"
+	statusStartFailed = "start failed"
+	statusScheduled   = "scheduled"
+)
+
+// statusEvent is a snapshot of a command's live state, published by the
+// worker goroutines whenever it changes.
+type statusEvent struct {
+	Phase       commandPhase
+	Name        string
+	Version     uint64
+	Label       string
+	State       string
+	Running     bool
+	PID         int
+	StartedAt   time.Time
+	Runtime     time.Duration
+	Restarts    int
+	Attempt     int
+	NextAttempt int
+	MaxAttempts int
+	// Runs counts the runs of a scheduled command, NextRun plans the next.
+	Runs    int
+	NextRun time.Time
+	Signal  string
+	Usage   processUsage
+}
+
+// finishedLabel describes a command whose process is not running.
+func finishedLabel(state string, exited bool, exitCode int, signal string) string {
+	switch state {
+	case recordPending, recordAborted:
+		return statusWaiting
+	case recordTimedOut:
+		return statusTimedOut
+	case recordStopped:
+		return statusStopped
+	case recordInterrupted:
+		return statusInterrupted
+	case recordStartFailed:
+		return statusStartFailed
+	}
+	if !exited {
+		return state
+	}
+	if signal != "" {
+		return fmt.Sprintf("exited(%s)", signal)
+	}
+	return fmt.Sprintf("exited(%d)", exitCode)
+}
+
+// statusColor returns the tview color name used to render a status label.
+func statusColor(ev statusEvent) string {
+	switch ev.Label {
+	case statusReady:
+		return "lime"
+	case statusRunning:
+		return "green"
+	case statusWaiting, statusStarting:
+		return "yellow"
+	case statusRestarting:
+		return "orange"
+	case statusTimedOut:
+		return "fuchsia"
+	case statusStopped, statusInterrupted:
+		return "gray"
+	case statusScheduled:
+		if ev.State == recordCompleted || ev.State == recordPending {
+			return "teal"
+		}
+		return "red"
+	case "exited(0)":
+		return "white"
+	default:
+		return "red"
+	}
+}
+
+// panelTitle renders the title of a command panel from its latest status.
+func panelTitle(name string, ev statusEvent, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, " %s [%s]● %s[-]", name, statusColor(ev), ev.Label)
+	if ev.Running {
+		fmt.Fprintf(&b, " pid %d up %s", ev.PID, formatUptime(now.Sub(ev.StartedAt)))
+		if ev.Usage.Threads > 0 {
+			fmt.Fprintf(&b, " %s", usageLabel(ev.Usage))
+		}
+	}
+	if ev.Runs > 0 {
+		fmt.Fprintf(&b, " run %d", ev.Runs)
+	}
+	if ev.Restarts > 0 {
+		fmt.Fprintf(&b, " ↻%d", ev.Restarts)
+	}
+	if ev.Label == statusScheduled {
+		fmt.Fprintf(&b, " next in %s", formatUptime(ev.NextRun.Sub(now)))
+	}
+	if ev.Label == statusRestarting {
+		if ev.MaxAttempts > 0 {
+			fmt.Fprintf(&b, " attempt %d/%d", ev.NextAttempt, ev.MaxAttempts)
+		} else {
+			fmt.Fprintf(&b, " attempt %d", ev.NextAttempt)
+		}
+	}
+	b.WriteString(" ")
+	return b.String()
+}
+
+// formatUptime renders a duration with second precision, e.g. 1h02m03s.
+func formatUptime(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	if d < 0 {
+		d = 0
+	}
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	sec := int(d.Seconds()) % 60
+	switch {
+	case h > 0:
+		return fmt.Sprintf("%dh%02dm%02ds", h, m, sec)
+	case m > 0:
+		return fmt.Sprintf("%dm%02ds", m, sec)
+	default:
+		return fmt.Sprintf("%ds", sec)
+	}
+}
+
+// statusCounts renders the status bar summary, e.g. "2 running · 1 exited(1)".
+func statusCounts(order []string, statuses map[string]statusEvent) string {
+	counts := make(map[string]int)
+	var labels []string
+	for _, name := range order {
+		ev, ok := statuses[name]
+		if !ok {
+			continue
+		}
+		label := ev.Label
+		if strings.HasPrefix(label, "exited(") {
+			label = "exited"
+		}
+		if counts[label] == 0 {
+			labels = append(labels, label)
+		}
+		counts[label]++
+	}
+	parts := make([]string, 0, len(labels))
+	for _, label := range labels {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[label], label))
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestFinishedLabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    string
+		exited   bool
+		exitCode int
+		signal   string
+		want     string
+	}{
+		{name: "pending", state: recordPending, want: statusWaiting},
+		{name: "timed out", state: recordTimedOut, exited: true, signal: "killed", want: statusTimedOut},
+		{name: "stopped", state: recordStopped, exited: true, want: statusStopped},
+		{name: "start failed", state: recordStartFailed, want: statusStartFailed},
+		{name: "exit code", state: recordFailed, exited: true, exitCode: 2, want: "exited(2)"},
+		{name: "success", state: recordCompleted, exited: true, want: "exited(0)"},
+		{name: "signal", state: recordFailed, exited: true, exitCode: -1, signal: "killed", want: "exited(killed)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := finishedLabel(tt.state, tt.exited, tt.exitCode, tt.signal); got != tt.want {
+				t.Errorf("finishedLabel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPanelTitle(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		ev   statusEvent
+		want string
+	}{
+		{
+			name: "running",
+			ev:   statusEvent{Label: statusRunning, Running: true, PID: 12, StartedAt: now.Add(-3 * time.Second)},
+			want: " api [green]● running[-] pid 12 up 3s ",
+		},
+		{
+			name: "running with usage",
+			ev:   statusEvent{Label: statusRunning, Running: true, PID: 12, StartedAt: now, Usage: processUsage{CPUPercent: 12.5, RSS: 3 << 20, Threads: 4}},
+			want: " api [green]● running[-] pid 12 up 0s 12.5% 3.0MiB 4t ",
+		},
+		{
+			name: "restarting with limit",
+			ev:   statusEvent{Label: statusRestarting, Restarts: 1, NextAttempt: 3, MaxAttempts: 4},
+			want: " api [orange]● restarting[-] ↻1 attempt 3/4 ",
+		},
+		{
+			name: "restarting unlimited",
+			ev:   statusEvent{Label: statusRestarting, NextAttempt: 2},
+			want: " api [orange]● restarting[-] attempt 2 ",
+		},
+		{
+			name: "failed",
+			ev:   statusEvent{Label: "exited(1)"},
+			want: " api [red]● exited(1)[-] ",
+		},
+		{
+			name: "scheduled",
+			ev:   statusEvent{Label: statusScheduled, State: recordCompleted, Runs: 3, NextRun: now.Add(90 * time.Second)},
+			want: " api [teal]● scheduled[-] run 3 next in 1m30s ",
+		},
+		{
+			name: "scheduled after a failed run",
+			ev:   statusEvent{Label: statusScheduled, State: recordFailed, Runs: 2, Restarts: 1, NextRun: now.Add(5 * time.Second)},
+			want: " api [red]● scheduled[-] run 2 ↻1 next in 5s ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := panelTitle("api", tt.ev, now); got != tt.want {
+				t.Errorf("panelTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatUptime(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{-time.Second, "0s"},
+		{1500 * time.Millisecond, "1s"},
+		{65 * time.Second, "1m05s"},
+		{time.Hour + 2*time.Minute + 3*time.Second, "1h02m03s"},
+	}
+	for _, tt := range tests {
+		if got := formatUptime(tt.d); got != tt.want {
+			t.Errorf("formatUptime(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestStatusCounts(t *testing.T) {
+	statuses := map[string]statusEvent{
+		"a": {Label: statusRunning},
+		"b": {Label: "exited(1)"},
+		"c": {Label: statusRunning},
+		"d": {Label: "exited(0)"},
+	}
+	got := statusCounts([]string{basePanelName, "a", "b", "c", "d"}, statuses)
+	if want := "2 running · 2 exited"; got != want {
+		t.Errorf("statusCounts() = %q, want %q", got, want)
+	}
+}
+
+func TestRunSummarySubscribe(t *testing.T) {
+	summary := newRunSummary()
+	events := summary.Subscribe()
+	rec := summary.Track(phaseMain, "api")
+
+	rec.attemptStarting()
+	rec.attemptStarted(99)
+	rec.attemptFinished(attemptResult{err: exec.Command("false").Run()})
+	rec.restartScheduled(2, 0)
+
+	want := []string{statusStarting, statusRunning, "exited(1)", statusRestarting}
+	var last uint64
+	for i, label := range want {
+		ev := <-events
+		if ev.Label != label {
+			t.Errorf("event %d label = %q, want %q", i, ev.Label, label)
+		}
+		if ev.Version <= last {
+			t.Errorf("event %d version %d is not increasing", i, ev.Version)
+		}
+		last = ev.Version
+		if i == 1 && ev.PID != 99 {
+			t.Errorf("expected pid 99 on running event, got %d", ev.PID)
+		}
+	}
+}
@@ -0,0 +1,681 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// commandPhase identifies the lifecycle stage a command belongs to.
+type commandPhase string
+
+const (
+	phaseSetup    commandPhase = "setup"
+	phaseMain     commandPhase = "main"
+	phaseShutdown commandPhase = "shutdown"
+)
+
+const (
+	recordPending     = "pending"
+	recordRunning     = "running"
+	recordCompleted   = "completed"
+	recordFailed      = "failed"
+	recordStartFailed = "start failed"
+	recordTimedOut    = "timed out"
+	recordInterrupted = "interrupted"
+	recordAborted     = "aborted"
+	recordStopped     = "stopped"
+)
+
+// runSummary collects the execution records of every command in a run.
+type runSummary struct {
+	mu           sync.Mutex
+	records      []*commandRecord
+	killOthersBy string
+	tailLines    int
+	stats        bool
+	logs         *logHub
+	events       chan statusEvent
+}
+
+// commandRecord accumulates the execution history of a single command.
+type commandRecord struct {
+	mu             sync.Mutex
+	summary        *runSummary
+	phase          commandPhase
+	name           string
+	state          string
+	exitCode       int
+	signal         string
+	failure        string
+	exited         bool
+	attempts       int
+	totalRuntime   time.Duration
+	lastRuntime    time.Duration
+	ready          bool
+	timeToReady    time.Duration
+	firstStarted   time.Time
+	killedByOthers bool
+	// runs and skipped count the runs of a scheduled command.
+	runs       int
+	skipped    int
+	stdoutTail *lineTail
+	stderrTail *lineTail
+	logs       *logHub
+
+	// Live status, published to status subscribers on every change.
+	version         uint64
+	pid             int
+	startedAt       time.Time
+	running         bool
+	starting        bool
+	restarting      bool
+	nextAttempt     int
+	maxAttempts     int
+	hasReadyPattern bool
+	attemptReady    bool
+	nextRun         time.Time
+
+	// Resource usage, sampled while stats are enabled. cpuTime adds up the
+	// CPU time of the finished attempts, their descendants included.
+	stats    bool
+	usage    processUsage
+	cpuTicks map[int]uint64
+	cpuTime  time.Duration
+	peakRSS  uint64
+}
+
+// commandSummary is the serializable snapshot of a commandRecord.
+type commandSummary struct {
+	Phase          commandPhase `json:"phase"`
+	Name           string       `json:"name"`
+	State          string       `json:"state"`
+	ExitCode       *int         `json:"exitCode,omitempty"`
+	Signal         string       `json:"signal,omitempty"`
+	Failure        string       `json:"failure,omitempty"`
+	Attempts       int          `json:"attempts"`
+	Restarts       int          `json:"restarts"`
+	TotalRuntimeMs int64        `json:"totalRuntimeMs"`
+	LastRuntimeMs  int64        `json:"lastRuntimeMs"`
+	TimeToReadyMs  *int64       `json:"timeToReadyMs,omitempty"`
+	KilledByOthers bool         `json:"killedByOthers"`
+	Runs           int          `json:"runs,omitempty"`
+	SkippedRuns    int          `json:"skippedRuns,omitempty"`
+	CPUTimeMs      *int64       `json:"cpuTimeMs,omitempty"`
+	PeakRSSBytes   *uint64      `json:"peakRssBytes,omitempty"`
+	StdoutTail     []string     `json:"-"`
+	StderrTail     []string     `json:"-"`
+}
+
+// lineTail keeps the most recent lines written to a stream.
+type lineTail struct {
+	limit int
+	lines []string
+}
+
+func newRunSummary() *runSummary {
+	return &runSummary{}
+}
+
+// Track registers a new command record. A nil summary returns a nil record, which ignores all updates.
+func (s *runSummary) Track(phase commandPhase, name string) *commandRecord {
+	if s == nil {
+		return nil
+	}
+	rec := &commandRecord{
+		summary: s,
+		phase:   phase,
+		name:    name,
+		state:   recordPending,
+	}
+	s.mu.Lock()
+	if s.tailLines > 0 {
+		rec.stdoutTail = &lineTail{limit: s.tailLines}
+		rec.stderrTail = &lineTail{limit: s.tailLines}
+	}
+	rec.logs = s.logs
+	rec.stats = s.stats
+	s.records = append(s.records, rec)
+	s.mu.Unlock()
+	return rec
+}
+
+// CaptureOutput makes records tracked afterwards keep the last n lines of stdout and stderr.
+func (s *runSummary) CaptureOutput(n int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tailLines = n
+}
+
+// MeasureUsage makes records tracked afterwards report their CPU time and peak memory.
+func (s *runSummary) MeasureUsage() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats = true
+}
+
+// StreamLogs makes records tracked afterwards publish their output lines to hub.
+func (s *runSummary) StreamLogs(hub *logHub) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logs = hub
+}
+
+// Subscribe returns a channel receiving a statusEvent for every state change of
+// every record. The subscriber must keep draining it, since workers block
+// while it is full.
+func (s *runSummary) Subscribe() <-chan statusEvent {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.events == nil {
+		s.events = make(chan statusEvent, statusEventBuffer)
+	}
+	return s.events
+}
+
+func (s *runSummary) eventChannel() chan statusEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.events
+}
+
+// MarkKillOthers remembers the command that triggered killOthers so that
+// processes interrupted afterwards can be attributed to it.
+func (s *runSummary) MarkKillOthers(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.killOthersBy == "" {
+		s.killOthersBy = name
+	}
+}
+
+func (s *runSummary) killOthersTriggered() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.killOthersBy != ""
+}
+
+// Snapshot returns the summaries of all tracked commands in registration order.
+func (s *runSummary) Snapshot() []commandSummary {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	records := append([]*commandRecord(nil), s.records...)
+	s.mu.Unlock()
+	out := make([]commandSummary, 0, len(records))
+	for _, rec := range records {
+		out = append(out, rec.snapshot())
+	}
+	return out
+}
+
+// attemptStarting marks the record as launching a new process.
+func (r *commandRecord) attemptStarting() {
+	if r == nil {
+		return
+	}
+	defer r.publish()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+	r.starting = true
+	r.restarting = false
+}
+
+func (r *commandRecord) attemptStarted(pid int) {
+	if r == nil {
+		return
+	}
+	defer r.publish()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+	r.attempts++
+	r.state = recordRunning
+	r.pid = pid
+	r.startedAt = time.Now()
+	if r.firstStarted.IsZero() {
+		r.firstStarted = r.startedAt
+	}
+	r.running = true
+	r.starting = false
+	r.attemptReady = false
+}
+
+// expectReadyPattern declares that readiness is signaled by output rather than by the process start.
+func (r *commandRecord) expectReadyPattern() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hasReadyPattern = true
+}
+
+// markReady records the time elapsed between the first start and the first readiness signal.
+func (r *commandRecord) markReady() {
+	if r == nil {
+		return
+	}
+	defer r.publish()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+	r.attemptReady = true
+	if r.ready {
+		return
+	}
+	r.ready = true
+	r.timeToReady = time.Since(r.firstStarted)
+}
+
+// restartScheduled marks the record as waiting to start the given attempt.
+// maxAttempts is zero when restarts are unlimited.
+func (r *commandRecord) restartScheduled(attempt, maxAttempts int) {
+	if r == nil {
+		return
+	}
+	defer r.publish()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+	r.restarting = true
+	r.nextAttempt = attempt
+	r.maxAttempts = maxAttempts
+}
+
+// runPlanned records the time of the next run of a scheduled command.
+func (r *commandRecord) runPlanned(at time.Time) {
+	if r == nil {
+		return
+	}
+	defer r.publish()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+	r.nextRun = at
+}
+
+// runStarted counts a run of a scheduled command, whose attempts are its
+// first process and the retries of the run.
+func (r *commandRecord) runStarted() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs++
+}
+
+// runSkipped counts a scheduled run dropped by the overlap policy.
+func (r *commandRecord) runSkipped() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skipped++
+}
+
+// restarts returns the number of processes started again, within their run
+// for scheduled commands.
+func (r *commandRecord) restarts() int {
+	if r.runs > 0 {
+		return max(r.attempts-r.runs, 0)
+	}
+	return max(r.attempts-1, 0)
+}
+
+// triggerKillOthers attributes a killOthers stop to this command.
+func (r *commandRecord) triggerKillOthers() {
+	if r == nil || r.summary == nil {
+		return
+	}
+	r.summary.MarkKillOthers(r.name)
+}
+
+func (r *commandRecord) startFailed() {
+	if r == nil {
+		return
+	}
+	defer r.publish()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+	r.attempts++
+	r.state = recordStartFailed
+	r.exited = false
+	r.signal = ""
+	r.failure = ""
+	r.starting = false
+}
+
+func (r *commandRecord) attemptFinished(res attemptResult) {
+	if r == nil {
+		return
+	}
+	killOthers := r.summary != nil && r.summary.killOthersTriggered()
+	defer r.publish()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+	r.running = false
+	r.attemptReady = false
+	r.usage = processUsage{}
+	r.lastRuntime = res.runtime
+	r.totalRuntime += res.runtime
+	r.cpuTime += res.cpuTime
+	r.exitCode, r.signal = exitDetails(res.err)
+	r.failure = res.failure
+	r.exited = true
+	switch {
+	case res.interrupted:
+		r.state = recordInterrupted
+		r.killedByOthers = killOthers
+	case res.action == actionStop, res.action == actionKill, res.action == actionRestart, res.action == actionRemove:
+		r.state = recordStopped
+	case res.timedOut:
+		r.state = recordTimedOut
+	case res.failure != "", res.err != nil:
+		r.state = recordFailed
+	default:
+		r.state = recordCompleted
+	}
+}
+
+// markAborted flags a command that was stopped before (re)starting.
+func (r *commandRecord) markAborted() {
+	if r == nil {
+		return
+	}
+	killOthers := r.summary != nil && r.summary.killOthersTriggered()
+	defer r.publish()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+	r.restarting = false
+	if r.attempts == 0 {
+		r.state = recordAborted
+	}
+	r.killedByOthers = killOthers
+}
+
+// publish sends the current status to the subscriber, if any. It must be
+// called without holding r.mu.
+func (r *commandRecord) publish() {
+	if r.summary == nil {
+		return
+	}
+	ch := r.summary.eventChannel()
+	if ch == nil {
+		return
+	}
+	ch <- r.status()
+}
+
+func (r *commandRecord) status() statusEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ev := statusEvent{
+		Phase:       r.phase,
+		Name:        r.name,
+		Version:     r.version,
+		State:       r.state,
+		Running:     r.running,
+		PID:         r.pid,
+		StartedAt:   r.startedAt,
+		Runtime:     r.totalRuntime,
+		Restarts:    r.restarts(),
+		Attempt:     r.attempts,
+		Runs:        r.runs,
+		NextRun:     r.nextRun,
+		NextAttempt: r.nextAttempt,
+		MaxAttempts: r.maxAttempts,
+		Signal:      r.signal,
+	}
+	if r.running {
+		ev.Usage = r.usage
+	}
+	switch {
+	case r.starting:
+		ev.Label = statusStarting
+	case r.restarting:
+		ev.Label = statusRestarting
+	case r.running && r.hasReadyPattern && r.attemptReady:
+		ev.Label = statusReady
+	case r.running:
+		ev.Label = statusRunning
+	case !r.nextRun.IsZero() && r.state != recordStopped && r.state != recordInterrupted:
+		ev.Label = statusScheduled
+	default:
+		ev.Label = finishedLabel(r.state, r.exited, r.exitCode, r.signal)
+	}
+	return ev
+}
+
+// captureOutput wraps the output writers so that emitted lines are kept, without
+// escape sequences, in the record's tails and published to its log hub.
+func captureOutput(rec *commandRecord, stdoutWriter, stderrWriter func(string)) (func(string), func(string)) {
+	if rec == nil || (rec.stdoutTail == nil && rec.logs == nil) {
+		return stdoutWriter, stderrWriter
+	}
+	wrap := func(stream string, tail *lineTail, writeLine func(string)) func(string) {
+		if writeLine == nil {
+			return nil
+		}
+		return func(line string) {
+			clean := stripANSI(line)
+			if tail != nil {
+				rec.mu.Lock()
+				tail.add(clean)
+				rec.mu.Unlock()
+			}
+			rec.logs.publish(rec.name, stream, clean)
+			writeLine(line)
+		}
+	}
+	return wrap(streamStdout, rec.stdoutTail, stdoutWriter), wrap(streamStderr, rec.stderrTail, stderrWriter)
+}
+
+func (t *lineTail) add(line string) {
+	if len(t.lines) >= t.limit {
+		t.lines = append(t.lines[:0], t.lines[1:]...)
+	}
+	t.lines = append(t.lines, line)
+}
+
+func (t *lineTail) snapshot() []string {
+	if t == nil {
+		return nil
+	}
+	return append([]string(nil), t.lines...)
+}
+
+func (r *commandRecord) snapshot() commandSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := commandSummary{
+		Phase:          r.phase,
+		Name:           r.name,
+		State:          r.state,
+		Signal:         r.signal,
+		Failure:        r.failure,
+		Attempts:       r.attempts,
+		TotalRuntimeMs: r.totalRuntime.Milliseconds(),
+		LastRuntimeMs:  r.lastRuntime.Milliseconds(),
+		KilledByOthers: r.killedByOthers,
+		Restarts:       r.restarts(),
+		Runs:           r.runs,
+		SkippedRuns:    r.skipped,
+		StdoutTail:     r.stdoutTail.snapshot(),
+		StderrTail:     r.stderrTail.snapshot(),
+	}
+	if r.exited && r.signal == "" {
+		code := r.exitCode
+		s.ExitCode = &code
+	}
+	if r.ready {
+		ms := r.timeToReady.Milliseconds()
+		s.TimeToReadyMs = &ms
+	}
+	if r.stats {
+		ms := r.cpuTime.Milliseconds()
+		s.CPUTimeMs = &ms
+	}
+	if r.cpuTicks != nil {
+		peak := r.peakRSS
+		s.PeakRSSBytes = &peak
+	}
+	return s
+}
+
+// exitDetails extracts the exit code or terminating signal from a process wait error.
+func exitDetails(err error) (code int, signal string) {
+	if err == nil {
+		return 0, ""
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return -1, ""
+	}
+	if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return -1, ws.Signal().String()
+	}
+	return exitErr.ExitCode(), ""
+}
+
+// reportSummary prints and stores the end-of-run summary according to the configuration.
+func reportSummary(cfg Config, summary *runSummary) {
+	if !cfg.Summary && cfg.SummaryFile == "" && !cfg.Report.enabled() {
+		return
+	}
+	rows := summary.Snapshot()
+	if cfg.Summary {
+		if err := writeSummaryTable(os.Stderr, rows); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to print summary: %v\n", err) //nolint:errcheck
+		}
+	}
+	if cfg.SummaryFile != "" {
+		if err := writeSummaryFile(cfg.SummaryFile, rows); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write summary file: %v\n", err) //nolint:errcheck
+		}
+	}
+	if err := writeReports(cfg.Report, rows); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err) //nolint:errcheck
+	}
+}
+
+// writeSummaryTable renders the run summary as an aligned text table. The runs
+// columns are added when commands were scheduled, the CPU time and peak memory
+// ones when process stats were sampled.
+func writeSummaryTable(w io.Writer, rows []commandSummary) error {
+	stats, scheduled := false, false
+	for _, row := range rows {
+		stats = stats || row.CPUTimeMs != nil
+		scheduled = scheduled || row.Runs > 0
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := "PHASE\tNAME\tSTATE\tEXIT\tRESTARTS\tTOTAL\tLAST\tREADY\tKILLED"
+	if scheduled {
+		header += "\tRUNS\tSKIPPED"
+	}
+	if stats {
+		header += "\tCPU\tPEAK RSS"
+	}
+	fmt.Fprintln(tw, header) //nolint:errcheck
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s", //nolint:errcheck
+			row.Phase,
+			row.Name,
+			row.State,
+			formatExit(row),
+			row.Restarts,
+			formatMillis(row.TotalRuntimeMs),
+			formatMillis(row.LastRuntimeMs),
+			formatOptionalMillis(row.TimeToReadyMs),
+			formatKilled(row.KilledByOthers),
+		)
+		if scheduled {
+			fmt.Fprintf(tw, "\t%d\t%d", row.Runs, row.SkippedRuns) //nolint:errcheck
+		}
+		if stats {
+			fmt.Fprintf(tw, "\t%s\t%s", formatOptionalMillis(row.CPUTimeMs), formatOptionalBytes(row.PeakRSSBytes)) //nolint:errcheck
+		}
+		fmt.Fprintln(tw) //nolint:errcheck
+	}
+	return tw.Flush()
+}
+
+// writeSummaryFile stores the run summary as JSON at path.
+func writeSummaryFile(path string, rows []commandSummary) error {
+	data, err := json.MarshalIndent(struct {
+		Commands []commandSummary `json:"commands"`
+	}{Commands: rows}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode summary: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write summary: %w", err)
+	}
+	return nil
+}
+
+func formatExit(row commandSummary) string {
+	switch {
+	case row.Signal != "":
+		return row.Signal
+	case row.ExitCode != nil:
+		return strconv.Itoa(*row.ExitCode)
+	default:
+		return "-"
+	}
+}
+
+func formatMillis(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+func formatOptionalBytes(n *uint64) string {
+	if n == nil {
+		return "-"
+	}
+	return formatBytes(*n)
+}
+
+func formatOptionalMillis(ms *int64) string {
+	if ms == nil {
+		return "-"
+	}
+	return formatMillis(*ms)
+}
+
+func formatKilled(killed bool) string {
+	if killed {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExitDetails(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+	signalErr := exec.Command("sh", "-c", "kill -TERM $$").Run()
+
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   int
+		wantSignal string
+	}{
+		{name: "nil error", err: nil, wantCode: 0},
+		{name: "exit code", err: exitErr, wantCode: 3},
+		{name: "signal", err: signalErr, wantCode: -1, wantSignal: "terminated"},
+		{name: "other error", err: errors.New("boom"), wantCode: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, signal := exitDetails(tt.err)
+			if code != tt.wantCode || signal != tt.wantSignal {
+				t.Errorf("exitDetails() = (%d, %q), want (%d, %q)", code, signal, tt.wantCode, tt.wantSignal)
+			}
+		})
+	}
+}
+
+func TestRunSummaryRecords(t *testing.T) {
+	summary := newRunSummary()
+	setup := summary.Track(phaseSetup, "migrate")
+	api := summary.Track(phaseMain, "api")
+	worker := summary.Track(phaseMain, "worker")
+
+	setup.attemptStarted(0)
+	setup.markReady()
+	setup.attemptFinished(attemptResult{runtime: 20 * time.Millisecond})
+
+	api.attemptStarted(0)
+	api.attemptFinished(attemptResult{err: exec.Command("false").Run(), runtime: 10 * time.Millisecond})
+	api.attemptStarted(0)
+	api.attemptFinished(attemptResult{err: exec.Command("false").Run(), runtime: 30 * time.Millisecond})
+	api.triggerKillOthers()
+
+	worker.attemptStarted(0)
+	worker.attemptFinished(attemptResult{interrupted: true, runtime: 40 * time.Millisecond})
+
+	rows := summary.Snapshot()
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+
+	if rows[0].Phase != phaseSetup || rows[0].State != recordCompleted || rows[0].TimeToReadyMs == nil {
+		t.Errorf("unexpected setup row: %+v", rows[0])
+	}
+	if rows[1].Restarts != 1 || rows[1].TotalRuntimeMs != 40 || rows[1].LastRuntimeMs != 30 {
+		t.Errorf("unexpected api runtime accounting: %+v", rows[1])
+	}
+	if rows[1].ExitCode == nil || *rows[1].ExitCode != 1 || rows[1].KilledByOthers {
+		t.Errorf("unexpected api exit: %+v", rows[1])
+	}
+	if rows[2].State != recordInterrupted || !rows[2].KilledByOthers {
+		t.Errorf("expected worker to be killed by killOthers: %+v", rows[2])
+	}
+}
+
+func TestRecordFailRule(t *testing.T) {
+	rec := newRunSummary().Track(phaseMain, "api")
+	rec.attemptStarted(0)
+	rec.attemptFinished(attemptResult{action: actionFail, failure: `output matched "panic"`})
+	row := rec.snapshot()
+	if row.State != recordFailed || row.ExitCode == nil || *row.ExitCode != 0 || row.Failure != `output matched "panic"` {
+		t.Errorf("a fail rule must fail the command with its real exit status: %+v", row)
+	}
+	rec.attemptStarted(0)
+	rec.attemptFinished(attemptResult{})
+	if row := rec.snapshot(); row.State != recordCompleted || row.Failure != "" {
+		t.Errorf("the next attempt must clear the failure: %+v", row)
+	}
+}
+
+func TestTimeToReadyFromFirstStart(t *testing.T) {
+	rec := newRunSummary().Track(phaseSetup, "seed")
+	time.Sleep(50 * time.Millisecond)
+	rec.attemptStarted(0)
+	rec.markReady()
+	if got := rec.snapshot().TimeToReadyMs; got == nil || *got >= 50 {
+		t.Errorf("time to ready must not include the wait before the start, got %v", got)
+	}
+}
+
+func TestNilRecordIsNoop(t *testing.T) {
+	var summary *runSummary
+	rec := summary.Track(phaseMain, "noop")
+	rec.attemptStarted(0)
+	rec.markReady()
+	rec.attemptFinished(attemptResult{})
+	rec.markAborted()
+	rec.triggerKillOthers()
+	if rows := summary.Snapshot(); rows != nil {
+		t.Errorf("expected nil snapshot, got %v", rows)
+	}
+}
+
+func TestWriteSummaryTable(t *testing.T) {
+	code := 2
+	rows := []commandSummary{
+		{Phase: phaseMain, Name: "api", State: recordFailed, ExitCode: &code, Restarts: 1, TotalRuntimeMs: 1500},
+		{Phase: phaseMain, Name: "web", State: recordInterrupted, Signal: "terminated", KilledByOthers: true},
+	}
+	var buf bytes.Buffer
+	if err := writeSummaryTable(&buf, rows); err != nil {
+		t.Fatalf("writeSummaryTable() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"PHASE", "api", "failed", "1.5s", "terminated", "yes"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected table to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteSummaryTableStats(t *testing.T) {
+	cpu, peak := int64(2500), uint64(64<<20)
+	rows := []commandSummary{
+		{Phase: phaseMain, Name: "api", State: recordCompleted, CPUTimeMs: &cpu, PeakRSSBytes: &peak},
+		{Phase: phaseSetup, Name: "init", State: recordCompleted},
+	}
+	var buf bytes.Buffer
+	if err := writeSummaryTable(&buf, rows); err != nil {
+		t.Fatalf("writeSummaryTable() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"PEAK RSS", "2.5s", "64.0MiB"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected table to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteSummaryTableRuns(t *testing.T) {
+	rows := []commandSummary{
+		{Phase: phaseMain, Name: "warmer", State: recordCompleted, Runs: 12, SkippedRuns: 3},
+		{Phase: phaseMain, Name: "api", State: recordInterrupted},
+	}
+	var buf bytes.Buffer
+	if err := writeSummaryTable(&buf, rows); err != nil {
+		t.Fatalf("writeSummaryTable() error = %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if fields := strings.Fields(lines[0]); fields[len(fields)-2] != "RUNS" || fields[len(fields)-1] != "SKIPPED" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); fields[len(fields)-2] != "12" || fields[len(fields)-1] != "3" {
+		t.Errorf("row = %q", lines[1])
+	}
+}
+
+func TestRecordScheduledRuns(t *testing.T) {
+	rec := newRunSummary().Track(phaseMain, "warmer")
+	for range 2 {
+		rec.runStarted()
+		rec.attemptStarting()
+		rec.attemptStarted(42)
+		rec.attemptFinished(attemptResult{})
+	}
+	rec.attemptStarting()
+	rec.attemptStarted(43)
+	rec.attemptFinished(attemptResult{})
+	rec.runSkipped()
+	rec.runPlanned(time.Now().Add(time.Minute))
+
+	ev := rec.status()
+	if ev.Label != statusScheduled || ev.Runs != 2 || ev.Attempt != 3 || ev.Restarts != 1 {
+		t.Errorf("status = %+v", ev)
+	}
+	if s := rec.snapshot(); s.Runs != 2 || s.SkippedRuns != 1 || s.Restarts != 1 || s.Attempts != 3 {
+		t.Errorf("snapshot = %+v", s)
+	}
+}
+
+func TestWriteSummaryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.json")
+	rows := []commandSummary{{Phase: phaseSetup, Name: "init", State: recordCompleted}}
+	if err := writeSummaryFile(path, rows); err != nil {
+		t.Fatalf("writeSummaryFile() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read summary: %v", err)
+	}
+	var decoded struct {
+		Commands []commandSummary `json:"commands"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("decode summary: %v", err)
+	}
+	if len(decoded.Commands) != 1 || decoded.Commands[0].Name != "init" {
+		t.Errorf("unexpected decoded summary: %+v", decoded)
+	}
+}
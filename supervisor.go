@@ -0,0 +1,376 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var (
+	errUnknownCommand    = errors.New("unknown command")
+	errUnknownAction     = errors.New("unknown action")
+	errControlBusy       = errors.New("a request is already pending")
+	errReloadUnavailable = errors.New("reload needs goncurrently to be started with --config")
+	errInvalidConfig     = errors.New("invalid config")
+	errRunEnded          = errors.New("the run is ending")
+)
+
+// supervisor runs the workers of the main commands and keeps the registry of
+// their configs and records, which the control server lists and acts on and
+// config reloads update.
+type supervisor struct {
+	sink        outputRouter
+	signals     stopSignals
+	killTimeout time.Duration
+	killOthers  bool
+	requestStop func()
+	summary     *runSummary
+	controls    *processControl
+	input       *processInput
+	colors      []*color.Color
+	// resumable keeps stopped commands idle instead of ending their workers,
+	// so that they can be started again.
+	resumable bool
+	// config is the config the commands run with, reloaded from configPath.
+	config     Config
+	configPath string
+
+	reloadMu sync.Mutex
+	mu       sync.Mutex
+	commands []*supervisedCommand
+	launched int
+	active   int
+	ended    bool
+}
+
+// supervisedCommand is a main command registered with the supervisor.
+type supervisedCommand struct {
+	config  CommandConfig
+	rec     *commandRecord
+	palette *color.Color
+	// done is closed when the worker has ended.
+	done chan struct{}
+}
+
+// StartAll tracks the commands and runs their workers in the background.
+// Every worker is reserved before any starts, so that a command ending at
+// once does not end the run before the next ones are counted.
+func (s *supervisor) StartAll(commands []CommandConfig) {
+	reserved := make([]*supervisedCommand, 0, len(commands))
+	for _, c := range commands {
+		cmd := s.track(c)
+		if s.reserve() {
+			reserved = append(reserved, cmd)
+		}
+	}
+	for _, cmd := range reserved {
+		go s.work(cmd)
+	}
+}
+
+// track registers c with a new record.
+func (s *supervisor) track(c CommandConfig) *supervisedCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd := &supervisedCommand{
+		config:  c,
+		rec:     s.summary.Track(phaseMain, c.Name),
+		palette: s.colors[s.launched%len(s.colors)],
+		done:    make(chan struct{}),
+	}
+	s.launched++
+	s.commands = append(s.commands, cmd)
+	return cmd
+}
+
+// reserve counts a worker about to start. The sink counts the workers too,
+// so that waiting on it waits for every command to finish. Once every worker
+// has ended the run is over and reserve reports false.
+func (s *supervisor) reserve() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return false
+	}
+	s.active++
+	// A worker counted in active has not called Done yet, so the sink count
+	// cannot drop to zero before this Add.
+	s.sink.Add()
+	return true
+}
+
+// work runs the worker of cmd, which must have been reserved.
+func (s *supervisor) work(cmd *supervisedCommand) {
+	defer recoverPanic(cmd.config.Name + " worker")
+	defer func() {
+		s.mu.Lock()
+		s.active--
+		s.ended = s.active == 0
+		s.mu.Unlock()
+		close(cmd.done)
+		s.sink.Done()
+	}()
+	c := cmd.config
+	baseLog("[%s] worker initialized", c.Name)
+	runManagedCommand(c, commandColor(c, cmd.palette), s.sink, s.signals, s.killTimeout, s.killOthers, s.requestStop, cmd.rec, s.controls.Channel(c.Name), s.input, s.resumable)
+}
+
+// Commands returns the live status of the registered commands in start order.
+func (s *supervisor) Commands() []statusEvent {
+	s.mu.Lock()
+	commands := append([]*supervisedCommand(nil), s.commands...)
+	s.mu.Unlock()
+	statuses := make([]statusEvent, 0, len(commands))
+	for _, cmd := range commands {
+		if ev := cmd.rec.status(); ev.Name != "" {
+			statuses = append(statuses, ev)
+		}
+	}
+	return statuses
+}
+
+// Control sends action to the worker of name.
+func (s *supervisor) Control(name, action, reason string) error {
+	switch action {
+	case actionRestart, actionStop, actionStart, actionKill:
+	default:
+		return fmt.Errorf("%w %q", errUnknownAction, action)
+	}
+	if s.lookup(name) == nil {
+		return fmt.Errorf("%w %q", errUnknownCommand, name)
+	}
+	if !s.controls.Send(name, action, reason) {
+		return fmt.Errorf("%s: %w", name, errControlBusy)
+	}
+	return nil
+}
+
+// StopAll stops every command and ends the run, as the first interrupt does.
+func (s *supervisor) StopAll(reason string) {
+	baseLog("stop requested by %s", reason)
+	if s.requestStop != nil {
+		s.requestStop()
+	}
+}
+
+func (s *supervisor) has(name string) bool {
+	return s.lookup(name) != nil
+}
+
+func (s *supervisor) lookup(name string) *supervisedCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cmd := range s.commands {
+		if cmd.config.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Reload re-reads the config file and applies the difference by command
+// name: added commands start, removed ones stop and changed ones restart with
+// their new config, while the others keep running. An invalid config is
+// rejected before anything changes.
+func (s *supervisor) Reload(reason string) (reloadPlan, error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	if s.configPath == "" {
+		return reloadPlan{}, errReloadUnavailable
+	}
+	select {
+	case <-s.signals.stop:
+		return reloadPlan{}, errRunEnded
+	default:
+	}
+	cfg, err := loadConfigFile(s.configPath)
+	if err == nil {
+		err = prepareConfig(&cfg)
+	}
+	if err == nil {
+		err = uniqueNames(cfg.Commands)
+	}
+	if err != nil {
+		return reloadPlan{}, fmt.Errorf("%w: %v", errInvalidConfig, err)
+	}
+
+	s.mu.Lock()
+	current := make([]CommandConfig, 0, len(s.commands))
+	for _, cmd := range s.commands {
+		current = append(current, cmd.config)
+	}
+	s.mu.Unlock()
+	if err := uniqueNames(current); err != nil {
+		return reloadPlan{}, err
+	}
+	plan := planReload(current, cfg.Commands)
+	baseLog("reloading %s (%s): %s", s.configPath, reason, plan)
+	if settingsChanged(s.config, cfg) {
+		baseLog("changes to settings other than commands apply at the next start")
+	}
+
+	next := make(map[string]CommandConfig, len(cfg.Commands))
+	for _, c := range cfg.Commands {
+		next[c.Name] = c
+	}
+	tui, _ := s.sink.(*tuiRouter)
+	styles := tui.commandStyles(cfg.Commands)
+	// Commands are added first, so that removing the others cannot end the
+	// run on the way.
+	for _, name := range plan.Added {
+		if err := s.add(next[name], tui, styles); err != nil {
+			return plan, err
+		}
+	}
+	for _, name := range plan.Restarted {
+		if err := s.replace(next[name], tui, styles); err != nil {
+			return plan, err
+		}
+	}
+	for _, name := range plan.Removed {
+		s.remove(name, tui)
+	}
+	s.config = cfg
+	return plan, nil
+}
+
+func (s *supervisor) add(c CommandConfig, tui *tuiRouter, styles map[string]panelAppearance) error {
+	if !s.reserve() {
+		return errRunEnded
+	}
+	tui.AddPanel(c.Name, styles[c.Name])
+	go s.work(s.track(c))
+	return nil
+}
+
+// replace ends the worker of c and starts a new one with c, keeping its
+// record.
+func (s *supervisor) replace(c CommandConfig, tui *tuiRouter, styles map[string]panelAppearance) error {
+	old := s.lookup(c.Name)
+	if !s.reserve() {
+		return errRunEnded
+	}
+	s.end(old)
+	cmd := &supervisedCommand{config: c, rec: old.rec, palette: old.palette, done: make(chan struct{})}
+	s.mu.Lock()
+	for i, registered := range s.commands {
+		if registered == old {
+			s.commands[i] = cmd
+		}
+	}
+	s.mu.Unlock()
+	tui.AddPanel(c.Name, styles[c.Name])
+	go s.work(cmd)
+	return nil
+}
+
+// remove ends the worker of name and forgets the command.
+func (s *supervisor) remove(name string, tui *tuiRouter) {
+	cmd := s.lookup(name)
+	s.end(cmd)
+	s.mu.Lock()
+	for i, registered := range s.commands {
+		if registered == cmd {
+			s.commands = append(s.commands[:i], s.commands[i+1:]...)
+			break
+		}
+	}
+	s.mu.Unlock()
+	s.controls.Unregister(name)
+	tui.RemovePanel(name)
+}
+
+// end stops the process of cmd and waits for its worker to end.
+func (s *supervisor) end(cmd *supervisedCommand) {
+	select {
+	case <-cmd.done:
+	default:
+		s.controls.Deliver(cmd.config.Name, controlRequest{action: actionRemove, reason: "config reload"}, cmd.done)
+		<-cmd.done
+	}
+	// The request stays queued when the worker ended on its own meanwhile.
+	pendingStop(s.controls.Channel(cmd.config.Name))
+}
+
+// reloadPlan lists the commands a reload adds, removes, restarts and keeps.
+type reloadPlan struct {
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Restarted []string `json:"restarted"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// planReload compares the running commands with the ones of a new config by
+// name, in the order of the configs.
+func planReload(current, next []CommandConfig) reloadPlan {
+	plan := reloadPlan{Added: []string{}, Removed: []string{}, Restarted: []string{}, Unchanged: []string{}}
+	running := make(map[string]CommandConfig, len(current))
+	for _, c := range current {
+		running[c.Name] = c
+	}
+	kept := make(map[string]bool, len(next))
+	for _, c := range next {
+		old, ok := running[c.Name]
+		switch {
+		case !ok:
+			plan.Added = append(plan.Added, c.Name)
+		case reflect.DeepEqual(old, c):
+			plan.Unchanged = append(plan.Unchanged, c.Name)
+		default:
+			plan.Restarted = append(plan.Restarted, c.Name)
+		}
+		kept[c.Name] = true
+	}
+	for _, c := range current {
+		if !kept[c.Name] {
+			plan.Removed = append(plan.Removed, c.Name)
+		}
+	}
+	return plan
+}
+
+// String describes the plan for the log, e.g. "added api; restarted web;
+// 2 unchanged".
+func (p reloadPlan) String() string {
+	var parts []string
+	for _, group := range []struct {
+		label string
+		names []string
+	}{{"added", p.Added}, {"removed", p.Removed}, {"restarted", p.Restarted}} {
+		if len(group.names) > 0 {
+			parts = append(parts, group.label+" "+strings.Join(group.names, ", "))
+		}
+	}
+	if len(parts) == 0 {
+		return "no command changed"
+	}
+	if len(p.Unchanged) > 0 {
+		parts = append(parts, fmt.Sprintf("%d unchanged", len(p.Unchanged)))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// uniqueNames rejects commands sharing a name, which a reload could not tell
+// apart.
+func uniqueNames(commands []CommandConfig) error {
+	seen := make(map[string]bool, len(commands))
+	for _, c := range commands {
+		if seen[c.Name] {
+			return fmt.Errorf("reload needs unique command names, '%s' is used twice", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return nil
+}
+
+// settingsChanged reports whether the configs differ in more than their
+// main commands.
+func settingsChanged(old, next Config) bool {
+	old.Commands, next.Commands = nil, nil
+	return !reflect.DeepEqual(old, next)
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func newTestSupervisor(names ...string) *supervisor {
+	sup := &supervisor{
+		sink:     &recordingRouter{},
+		summary:  newRunSummary(),
+		controls: newProcessControl(names),
+		colors:   []*color.Color{color.New(color.FgCyan)},
+	}
+	for _, name := range names {
+		sup.commands = append(sup.commands, &supervisedCommand{
+			config: CommandConfig{Name: name},
+			rec:    sup.summary.Track(phaseMain, name),
+		})
+	}
+	return sup
+}
+
+func TestSupervisorControl(t *testing.T) {
+	sup := newTestSupervisor("api", "web")
+
+	tests := []struct {
+		name    string
+		command string
+		action  string
+		want    error
+	}{
+		{"restart", "api", actionRestart, nil},
+		{"pending request", "api", actionStop, errControlBusy},
+		{"unknown command", "db", actionStop, errUnknownCommand},
+		{"unknown action", "web", "pause", errUnknownAction},
+		{"fail is internal", "web", actionFail, errUnknownAction},
+		{"stop", "web", actionStop, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sup.Control(tt.command, tt.action, "test")
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Errorf("Control(%q, %q) = %v, want %v", tt.command, tt.action, err, tt.want)
+			}
+		})
+	}
+	if req := <-sup.controls.Channel("api"); req.action != actionRestart {
+		t.Errorf("api received %+v", req)
+	}
+}
+
+func TestSupervisorStartAndStop(t *testing.T) {
+	origErrorOutput := errorOutput
+	errorOutput = io.Discard
+	defer func() { errorOutput = origErrorOutput }()
+
+	sup := newTestSupervisor()
+	sup.killTimeout = 100 * time.Millisecond
+	sup.StartAll([]CommandConfig{{Name: "sleeper", Cmd: "sleep", Args: []string{"10"}}})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		statuses := sup.Commands()
+		if len(statuses) == 1 && statuses[0].Running && statuses[0].PID > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("command did not start: %+v", statuses)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := sup.Control("sleeper", actionStop, "test"); err != nil {
+		t.Fatalf("Control() error = %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		sup.sink.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("worker did not end after a stop request")
+	}
+	if ev := sup.Commands()[0]; ev.Running || ev.State != recordStopped {
+		t.Errorf("unexpected status after stop: %+v", ev)
+	}
+}
+
+// startOrderSink reports the workers started before every command of
+// StartAll was reserved: a command ending at once would end the run early.
+type startOrderSink struct {
+	recordingRouter
+	t       *testing.T
+	summary *runSummary
+	adds    int
+}
+
+func (s *startOrderSink) Add() {
+	s.recordingRouter.Add()
+	if s.adds++; s.adds == 1 {
+		return
+	}
+	deadline := time.Now().Add(100 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		for _, row := range s.summary.Snapshot() {
+			if row.Attempts > 0 {
+				s.t.Errorf("%s started before every command was reserved", row.Name)
+				return
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSupervisorStartAllReservesFirst(t *testing.T) {
+	origErrorOutput := errorOutput
+	errorOutput = io.Discard
+	defer func() { errorOutput = origErrorOutput }()
+
+	sup := newTestSupervisor()
+	sup.sink = &startOrderSink{t: t, summary: sup.summary}
+	sup.StartAll([]CommandConfig{
+		{Name: "broken", Cmd: "/nonexistent/command"},
+		{Name: "quick", Cmd: "true"},
+	})
+	sup.sink.Wait()
+	for _, ev := range sup.Commands() {
+		if ev.Attempt != 1 {
+			t.Errorf("%s did not run: %+v", ev.Name, ev)
+		}
+	}
+}
+
+func TestSupervisorStopAll(t *testing.T) {
+	origErrorOutput := errorOutput
+	errorOutput = io.Discard
+	defer func() { errorOutput = origErrorOutput }()
+
+	stopped := false
+	sup := newTestSupervisor("api")
+	sup.requestStop = func() { stopped = true }
+	sup.StopAll("test")
+	if !stopped {
+		t.Error("StopAll() did not request the stop")
+	}
+}
+
+func TestPlanReload(t *testing.T) {
+	api := CommandConfig{Name: "api", Cmd: "go", Args: []string{"run", "."}}
+	web := CommandConfig{Name: "web", Cmd: "npm"}
+	worker := CommandConfig{Name: "worker", Cmd: "worker"}
+	changedAPI := api
+	changedAPI.Args = []string{"run", "./cmd/api"}
+
+	tests := []struct {
+		name    string
+		current []CommandConfig
+		next    []CommandConfig
+		want    reloadPlan
+		text    string
+	}{
+		{
+			"unchanged",
+			[]CommandConfig{api, web},
+			[]CommandConfig{api, web},
+			reloadPlan{Added: []string{}, Removed: []string{}, Restarted: []string{}, Unchanged: []string{"api", "web"}},
+			"no command changed",
+		},
+		{
+			"added, removed and changed",
+			[]CommandConfig{api, web},
+			[]CommandConfig{changedAPI, worker},
+			reloadPlan{Added: []string{"worker"}, Removed: []string{"web"}, Restarted: []string{"api"}, Unchanged: []string{}},
+			"added worker; removed web; restarted api",
+		},
+		{
+			"reordered",
+			[]CommandConfig{api, web, worker},
+			[]CommandConfig{worker, web, changedAPI},
+			reloadPlan{Added: []string{}, Removed: []string{}, Restarted: []string{"api"}, Unchanged: []string{"worker", "web"}},
+			"restarted api; 2 unchanged",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := planReload(tt.current, tt.next)
+			if !reflect.DeepEqual(plan, tt.want) {
+				t.Errorf("planReload() = %+v, want %+v", plan, tt.want)
+			}
+			if got := plan.String(); got != tt.text {
+				t.Errorf("String() = %q, want %q", got, tt.text)
+			}
+		})
+	}
+}
+
+func TestUniqueNames(t *testing.T) {
+	if err := uniqueNames([]CommandConfig{{Name: "api"}, {Name: "web"}}); err != nil {
+		t.Errorf("uniqueNames() error = %v", err)
+	}
+	if err := uniqueNames([]CommandConfig{{Name: "npm"}, {Name: "npm"}}); err == nil {
+		t.Error("expected duplicate names to be rejected")
+	}
+}
+
+func TestSettingsChanged(t *testing.T) {
+	old := Config{Commands: []CommandConfig{{Name: "api"}}, KillTimeout: 100}
+	if settingsChanged(old, Config{Commands: []CommandConfig{{Name: "web"}}, KillTimeout: 100}) {
+		t.Error("changed commands must not count as changed settings")
+	}
+	if !settingsChanged(old, Config{Commands: old.Commands, KillTimeout: 200}) {
+		t.Error("expected killTimeout to count as a changed setting")
+	}
+}
+
+func sleeperConfig(commands ...string) string {
+	var b strings.Builder
+	b.WriteString("commands:\n")
+	for _, command := range commands {
+		name, seconds, _ := strings.Cut(command, "=")
+		fmt.Fprintf(&b, "  - name: %s\n    cmd: sleep\n    args: [%q]\n", name, seconds)
+	}
+	return b.String()
+}
+
+func waitForCommands(t *testing.T, sup *supervisor, want string) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	var got string
+	for time.Now().Before(deadline) {
+		var running []string
+		for _, ev := range sup.Commands() {
+			if ev.Running {
+				running = append(running, fmt.Sprintf("%s:%d", ev.Name, ev.Attempt))
+			}
+		}
+		if got = strings.Join(running, ","); got == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("running commands = %q, want %q", got, want)
+}
+
+func TestSupervisorReload(t *testing.T) {
+	origErrorOutput := errorOutput
+	errorOutput = io.Discard
+	defer func() { errorOutput = origErrorOutput }()
+
+	path := filepath.Join(t.TempDir(), "goncurrently.yaml")
+	if err := os.WriteFile(path, []byte(sleeperConfig("api=10", "web=10", "db=10")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfigFile(path)
+	if err != nil || prepareConfig(&cfg) != nil {
+		t.Fatalf("invalid test config: %v", err)
+	}
+	tm := newTerminationManager(nil)
+	defer tm.Shutdown()
+	sup := newTestSupervisor()
+	sup.signals = tm.StopSignals()
+	sup.killTimeout = 100 * time.Millisecond
+	sup.config = cfg
+	sup.configPath = path
+	sup.StartAll(cfg.Commands)
+	waitForCommands(t, sup, "api:1,web:1,db:1")
+
+	if err := os.WriteFile(path, []byte(sleeperConfig("api=10", "web=20", "worker=10")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := sup.Reload("test")
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got := plan.String(); got != "added worker; removed db; restarted web; 1 unchanged" {
+		t.Errorf("plan = %q", got)
+	}
+	waitForCommands(t, sup, "api:1,web:2,worker:1")
+	if names := sup.controls.Names(); slices.Contains(names, "db") {
+		t.Errorf("removed command still controllable: %v", names)
+	}
+
+	if err := os.WriteFile(path, []byte("commands:\n  - name: api\n    cmd: sleep\n    restartAfter: soon\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sup.Reload("test"); !errors.Is(err, errInvalidConfig) {
+		t.Errorf("Reload() error = %v, want an invalid config", err)
+	}
+	waitForCommands(t, sup, "api:1,web:2,worker:1")
+
+	tm.RequestStop()
+	done := make(chan struct{})
+	go func() {
+		sup.sink.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("workers did not end after the stop")
+	}
+	if _, err := sup.Reload("test"); !errors.Is(err, errRunEnded) {
+		t.Errorf("Reload() after the stop error = %v", err)
+	}
+}
+
+func TestSupervisorReloadWithoutConfigFile(t *testing.T) {
+	if _, err := newTestSupervisor("api").Reload("test"); !errors.Is(err, errReloadUnavailable) {
+		t.Errorf("Reload() error = %v, want %v", err, errReloadUnavailable)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Built-in theme presets.
+const (
+	themeDark         = "dark"
+	themeLight        = "light"
+	themeHighContrast = "high-contrast"
+)
+
+// ThemeConfig customizes the colors of the TUI. Fields left empty use the
+// preset, then the defaults.
+type ThemeConfig struct {
+	Preset           string `yaml:"preset" validate:"omitempty,oneof=dark light high-contrast"`
+	Base             string `yaml:"base"`
+	Border           string `yaml:"border"`
+	FocusedBorder    string `yaml:"focusedBorder"`
+	Background       string `yaml:"background"`
+	Text             string `yaml:"text"`
+	Status           string `yaml:"status"`
+	StatusBackground string `yaml:"statusBackground"`
+}
+
+var themePresets = map[string]ThemeConfig{
+	themeDark: {},
+	themeLight: {
+		Base:             "gray",
+		FocusedBorder:    "black",
+		Background:       "white",
+		Text:             "black",
+		Status:           "black",
+		StatusBackground: "silver",
+	},
+	themeHighContrast: {
+		Base:             "white",
+		Border:           "white",
+		FocusedBorder:    "yellow",
+		Background:       "black",
+		Text:             "white",
+		Status:           "black",
+		StatusBackground: "yellow",
+	},
+}
+
+// tuiTheme is a resolved theme; tcell.ColorDefault keeps the TUI default.
+type tuiTheme struct {
+	Base             tcell.Color
+	Border           tcell.Color
+	FocusedBorder    tcell.Color
+	Background       tcell.Color
+	Text             tcell.Color
+	Status           tcell.Color
+	StatusBackground tcell.Color
+}
+
+// newTUITheme resolves the colors of a theme over its preset.
+func newTUITheme(cfg ThemeConfig) (tuiTheme, error) {
+	preset := themePresets[cfg.Preset]
+	var theme tuiTheme
+	for _, field := range []struct {
+		name          string
+		value, preset string
+		color         *tcell.Color
+	}{
+		{"base", cfg.Base, preset.Base, &theme.Base},
+		{"border", cfg.Border, preset.Border, &theme.Border},
+		{"focusedBorder", cfg.FocusedBorder, preset.FocusedBorder, &theme.FocusedBorder},
+		{"background", cfg.Background, preset.Background, &theme.Background},
+		{"text", cfg.Text, preset.Text, &theme.Text},
+		{"status", cfg.Status, preset.Status, &theme.Status},
+		{"statusBackground", cfg.StatusBackground, preset.StatusBackground, &theme.StatusBackground},
+	} {
+		spec := field.value
+		if spec == "" {
+			spec = field.preset
+		}
+		if spec == "" {
+			*field.color = tcell.ColorDefault
+			continue
+		}
+		c, err := parseColor(spec)
+		if err != nil {
+			return tuiTheme{}, fmt.Errorf("theme.%s: %w", field.name, err)
+		}
+		*field.color = c
+	}
+	return theme, nil
+}
+
+// parseColor reads a color name, a #rrggbb hex value or a 256-color index.
+func parseColor(spec string) (tcell.Color, error) {
+	spec = strings.TrimSpace(spec)
+	if n, err := strconv.Atoi(spec); err == nil {
+		if n < 0 || n > 255 {
+			return tcell.ColorDefault, fmt.Errorf("color %d is not in the 0-255 range", n)
+		}
+		return tcell.PaletteColor(n), nil
+	}
+	if strings.EqualFold(spec, "default") {
+		return tcell.ColorDefault, nil
+	}
+	c := tcell.GetColor(strings.ToLower(spec))
+	if c == tcell.ColorDefault {
+		return tcell.ColorDefault, fmt.Errorf("unknown color %q", spec)
+	}
+	return c, nil
+}
+
+// validateCommandColors checks the color of every command.
+func validateCommandColors(groups ...[]CommandConfig) error {
+	for _, commands := range groups {
+		for _, c := range commands {
+			if c.Color == "" {
+				continue
+			}
+			if _, err := parseColor(c.Color); err != nil {
+				return fmt.Errorf("command '%s': %w", c.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// consoleColor converts a TUI color to the closest console escape sequence:
+// the 16 basic colors keep their usual codes so that terminal themes apply.
+func consoleColor(c tcell.Color) *color.Color {
+	index := int(c - tcell.ColorValid)
+	switch {
+	case c.IsRGB() || index > 255:
+		// Hex values and the named colors outside the 256-color palette.
+		r, g, b := c.RGB()
+		return color.RGB(int(r), int(g), int(b))
+	case index < 8:
+		return color.New(color.FgBlack + color.Attribute(index))
+	case index < 16:
+		return color.New(color.FgHiBlack + color.Attribute(index-8))
+	default:
+		return color.New(38, 5, color.Attribute(index))
+	}
+}
+
+// commandColor returns the console color of a command: its configured color,
+// or fallback from the default palette.
+func commandColor(c CommandConfig, fallback *color.Color) *color.Color {
+	if c.Color == "" {
+		return fallback
+	}
+	parsed, err := parseColor(c.Color)
+	if err != nil || parsed == tcell.ColorDefault {
+		return fallback
+	}
+	return consoleColor(parsed)
+}
+
+// applyTheme overrides the panel styles with the colors set by the theme.
+func applyTheme(styles map[string]panelAppearance, theme tuiTheme) {
+	for name, style := range styles {
+		if name == basePanelName && theme.Base != tcell.ColorDefault {
+			style.BorderColor = theme.Base
+			style.TitleColor = theme.Base
+		} else if name != basePanelName && theme.Border != tcell.ColorDefault {
+			style.BorderColor = theme.Border
+		}
+		style.FocusedBorderColor = theme.FocusedBorder
+		style.BackgroundColor = theme.Background
+		style.TextColor = theme.Text
+		styles[name] = style
+	}
+}
+
+// themedTextView sets the text and background colors of a view, keeping the
+// TUI defaults for tcell.ColorDefault.
+func themedTextView(view *tview.TextView, text, background tcell.Color) *tview.TextView {
+	if text != tcell.ColorDefault {
+		view.SetTextColor(text)
+	}
+	if background != tcell.ColorDefault {
+		view.SetBackgroundColor(background)
+	}
+	return view
+}
+
+// highlightFocus draws the border of the focused panel in the focused border
+// color of the theme. It must run on the UI goroutine.
+func (t *tuiRouter) highlightFocus() {
+	focused := t.visibleName()
+	for name, view := range t.views {
+		style := t.styles[name]
+		border := style.BorderColor
+		if name == focused && style.FocusedBorderColor != tcell.ColorDefault {
+			border = style.FocusedBorderColor
+		}
+		if border == tcell.ColorDefault {
+			border = tview.Styles.BorderColor
+		}
+		view.SetBorderColor(border)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    tcell.Color
+		wantErr bool
+	}{
+		{spec: "red", want: tcell.ColorRed},
+		{spec: "SpringGreen", want: tcell.ColorSpringGreen},
+		{spec: "#ff8800", want: tcell.NewHexColor(0xff8800)},
+		{spec: "208", want: tcell.PaletteColor(208)},
+		{spec: "default", want: tcell.ColorDefault},
+		{spec: "256", wantErr: true},
+		{spec: "blurple", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseColor(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseColor(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseColor(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestNewTUITheme(t *testing.T) {
+	theme, err := newTUITheme(ThemeConfig{Preset: themeHighContrast, FocusedBorder: "#0066cc"})
+	if err != nil {
+		t.Fatalf("newTUITheme() error = %v", err)
+	}
+	if theme.Background != tcell.ColorBlack || theme.Border != tcell.ColorWhite {
+		t.Errorf("expected the preset colors, got %+v", theme)
+	}
+	if theme.FocusedBorder != tcell.NewHexColor(0x0066cc) {
+		t.Errorf("FocusedBorder = %v, want the configured color", theme.FocusedBorder)
+	}
+
+	theme, err = newTUITheme(ThemeConfig{})
+	if err != nil || theme != (tuiTheme{}) {
+		t.Errorf("newTUITheme() without settings = %+v, %v, want the defaults", theme, err)
+	}
+
+	if _, err := newTUITheme(ThemeConfig{Status: "nope"}); err == nil {
+		t.Error("expected an error for an unknown status color")
+	}
+}
+
+func TestConsoleColor(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = false
+
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{spec: "olive", want: "\x1b[33mx\x1b["},
+		{spec: "red", want: "\x1b[91mx\x1b["},
+		{spec: "208", want: "\x1b[38;5;208mx\x1b["},
+		{spec: "#ff8800", want: "\x1b[38;2;255;136;0mx\x1b["},
+	}
+	for _, tt := range tests {
+		c, err := parseColor(tt.spec)
+		if err != nil {
+			t.Fatalf("parseColor(%q) error = %v", tt.spec, err)
+		}
+		if got := consoleColor(c).Sprint("x"); !strings.HasPrefix(got, tt.want) {
+			t.Errorf("consoleColor(%q) = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+
+	fallback := color.New(color.FgCyan)
+	if got := commandColor(CommandConfig{Name: "api"}, fallback); got != fallback {
+		t.Error("expected the palette color for a command without color")
+	}
+}
+
+func TestValidateCommandColors(t *testing.T) {
+	if err := validateCommandColors([]CommandConfig{{Name: "api", Color: "orange"}}, nil); err != nil {
+		t.Errorf("validateCommandColors() error = %v", err)
+	}
+	if err := validateCommandColors(nil, []CommandConfig{{Name: "seed", Color: "#12"}}); err == nil {
+		t.Error("expected an error for an invalid setup command color")
+	}
+}
+
+func TestApplyTheme(t *testing.T) {
+	styles := defaultPanelStyles([]CommandConfig{{Name: "api", Color: "orange"}, {Name: "web"}})
+	if styles["api"].BorderColor != tcell.ColorOrange {
+		t.Fatalf("api border = %v, want its configured color", styles["api"].BorderColor)
+	}
+	applyTheme(styles, tuiTheme{Base: tcell.ColorGray, FocusedBorder: tcell.ColorYellow, Background: tcell.ColorWhite})
+	if got := styles[basePanelName]; got.BorderColor != tcell.ColorGray || got.TitleColor != tcell.ColorGray {
+		t.Errorf("base panel style = %+v, want the theme base color", got)
+	}
+	if got := styles["api"]; got.BorderColor != tcell.ColorOrange || got.FocusedBorderColor != tcell.ColorYellow || got.BackgroundColor != tcell.ColorWhite {
+		t.Errorf("api style = %+v, want its color with the theme focus and background", got)
+	}
+}
+
+func TestTUIRouterFocusedBorder(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api", "web"}, tuiOptions{})
+	waitForScreen(t, router, screen, "web ● waiting")
+	router.app.QueueUpdateDraw(func() {
+		for name, style := range router.styles {
+			style.FocusedBorderColor = tcell.ColorYellow
+			router.styles[name] = style
+		}
+	})
+
+	screenAfter(router, screen, func() {
+		router.handleKey(tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone))
+	})
+	borders := make(chan [2]tcell.Color, 1)
+	router.app.QueueUpdate(func() {
+		borders <- [2]tcell.Color{router.views["api"].GetBorderColor(), router.views["web"].GetBorderColor()}
+	})
+	got := <-borders
+	if got[0] != tcell.ColorYellow {
+		t.Errorf("focused api border = %v, want yellow", got[0])
+	}
+	if got[1] == tcell.ColorYellow {
+		t.Error("unfocused web border should keep its color")
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+const (
+	// tuiFrameInterval is the delay between two renderings of buffered output.
+	tuiFrameInterval = time.Second / 30
+	// maxPendingLines bounds the lines waiting for a frame when the scrollback is unlimited.
+	maxPendingLines = 10000
+)
+
+// bufferedLine is output waiting to be written to a panel through the ANSI
+// translator of its stream, or translated on its own when writer is nil.
+type bufferedLine struct {
+	writer io.Writer
+	text   string
+}
+
+// ring keeps the last limit items pushed, counting the ones it dropped.
+type ring[T any] struct {
+	items   []T
+	start   int
+	dropped int
+	limit   int
+}
+
+func (r *ring[T]) push(item T) {
+	if len(r.items) < r.limit {
+		r.items = append(r.items, item)
+		return
+	}
+	// The ring is full: overwrite the oldest item.
+	r.items[r.start] = item
+	r.start = (r.start + 1) % r.limit
+	r.dropped++
+}
+
+// snapshot returns the items from the oldest to the newest.
+func (r *ring[T]) snapshot() []T {
+	items := make([]T, 0, len(r.items))
+	items = append(items, r.items[r.start:]...)
+	return append(items, r.items[:r.start]...)
+}
+
+func (r *ring[T]) reset() {
+	clear(r.items)
+	r.items = r.items[:0]
+	r.start = 0
+	r.dropped = 0
+}
+
+// panelBuffer collects the output of a panel between two frames. Once limit
+// lines are pending, the oldest ones are dropped.
+type panelBuffer struct {
+	mu    sync.Mutex
+	lines ring[bufferedLine]
+}
+
+func newPanelBuffer(limit int) *panelBuffer {
+	if limit <= 0 {
+		limit = maxPendingLines
+	}
+	return &panelBuffer{lines: ring[bufferedLine]{limit: limit}}
+}
+
+func (b *panelBuffer) push(line bufferedLine) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lines.push(line)
+}
+
+// take returns the pending lines in order and how many were dropped since the
+// previous call, emptying the buffer.
+func (b *panelBuffer) take() ([]bufferedLine, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.lines.items) == 0 {
+		return nil, 0
+	}
+	lines, dropped := b.lines.snapshot(), b.lines.dropped
+	b.lines.reset()
+	return lines, dropped
+}
+
+// enqueue buffers output for the panel name until the next frame.
+func (t *tuiRouter) enqueue(name string, line bufferedLine) {
+	buffer := t.buffers[name]
+	if buffer == nil {
+		return
+	}
+	buffer.push(line)
+	t.dirty.Store(true)
+}
+
+// renderFrames flushes the buffered output at most once per frame. At most one
+// flush is queued on the UI goroutine at a time, so a busy UI makes the
+// buffers grow, and drop their oldest lines, instead of the event queue.
+func (t *tuiRouter) renderFrames() {
+	defer recoverPanic("TUI frames")
+	ticker := time.NewTicker(tuiFrameInterval)
+	defer ticker.Stop()
+	spinner := time.NewTicker(stepSpinnerInterval)
+	defer spinner.Stop()
+	for {
+		select {
+		case <-spinner.C:
+			if t.stepsRunning.Load() {
+				t.stepsDirty.Store(true)
+				t.dirty.Store(true)
+			}
+		case <-ticker.C:
+			if !t.dirty.Load() || !t.flushQueued.CompareAndSwap(false, true) {
+				continue
+			}
+			t.dirty.Store(false)
+			t.app.QueueUpdateDraw(t.flush)
+		case <-t.runDone:
+			return
+		}
+	}
+}
+
+// flush writes the buffered output to the panels. It must run on the UI goroutine.
+func (t *tuiRouter) flush() {
+	t.flushQueued.Store(false)
+	if t.stepsDirty.Swap(false) {
+		t.renderSteps(time.Now())
+	}
+	for name, buffer := range t.buffers {
+		lines, dropped := buffer.take()
+		if len(lines) == 0 {
+			continue
+		}
+		view := t.views[name]
+		if dropped > 0 {
+			fmt.Fprintf(view, "[gray]… %d lines dropped[-]\n", dropped) //nolint:errcheck
+		}
+		for _, line := range lines {
+			if line.writer == nil {
+				// Lines from several streams are translated one by one.
+				fmt.Fprint(view, tview.TranslateANSI(line.text)) //nolint:errcheck
+				continue
+			}
+			fmt.Fprint(line.writer, line.text) //nolint:errcheck
+		}
+		if !t.paused[name] {
+			view.ScrollToEnd()
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+func TestPanelBuffer(t *testing.T) {
+	buffer := newPanelBuffer(3)
+	for i := 1; i <= 5; i++ {
+		buffer.push(bufferedLine{text: strconv.Itoa(i)})
+	}
+	lines, dropped := buffer.take()
+	if dropped != 2 {
+		t.Errorf("dropped = %d, want 2", dropped)
+	}
+	var got []string
+	for _, line := range lines {
+		got = append(got, line.text)
+	}
+	if fmt.Sprint(got) != "[3 4 5]" {
+		t.Errorf("lines = %v, want the newest [3 4 5]", got)
+	}
+
+	if lines, dropped := buffer.take(); len(lines) != 0 || dropped != 0 {
+		t.Errorf("expected an empty buffer after take, got %d lines, %d dropped", len(lines), dropped)
+	}
+	buffer.push(bufferedLine{text: "6"})
+	if lines, _ := buffer.take(); len(lines) != 1 || lines[0].text != "6" {
+		t.Errorf("expected the buffer to be reusable, got %+v", lines)
+	}
+}
+
+func TestNewPanelBufferUnlimited(t *testing.T) {
+	if got := newPanelBuffer(0).lines.limit; got != maxPendingLines {
+		t.Errorf("limit = %d, want %d", got, maxPendingLines)
+	}
+}
+
+func TestTUIRouterDropsOldestPendingLines(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{Scrollback: 5})
+	write := router.LineWriter("api", nil, "")
+	// Holding the UI goroutine makes every line wait for the same frame.
+	router.app.QueueUpdate(func() {
+		for i := 1; i <= 20; i++ {
+			write(fmt.Sprintf("line %d", i))
+		}
+	})
+	waitForScreen(t, router, screen, "… 16 lines dropped")
+	waitForScreen(t, router, screen, "line 20")
+}
+
+// perLineWriter reproduces the former LineWriter, which queued a redraw for
+// every single line.
+func perLineWriter(router *tuiRouter, name string) func(string) {
+	view := router.views[name]
+	writer := tview.ANSIWriter(view)
+	return func(line string) {
+		text := escapeTviewText(line)
+		router.app.QueueUpdateDraw(func() {
+			fmt.Fprintf(writer, lineJoinFormat, "", text) //nolint:errcheck
+			view.ScrollToEnd()
+		})
+	}
+}
+
+// drainTUI waits until every line written so far has been drawn.
+func drainTUI(router *tuiRouter) {
+	for router.dirty.Load() || router.flushQueued.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	done := make(chan struct{})
+	router.app.QueueUpdateDraw(func() { close(done) })
+	<-done
+}
+
+func BenchmarkTUILineWriter(b *testing.B) {
+	writers := map[string]func(*tuiRouter) func(string){
+		"batched": func(router *tuiRouter) func(string) {
+			return router.LineWriter("api", nil, "")
+		},
+		"per-line": func(router *tuiRouter) func(string) {
+			return perLineWriter(router, "api")
+		},
+	}
+	for _, name := range []string{"batched", "per-line"} {
+		b.Run(name, func(b *testing.B) {
+			router, _ := newSimulatedTUIRouter(b, []string{"api"}, tuiOptions{})
+			write := writers[name](router)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				write("GET /api/items 200 1.2ms request served")
+			}
+			drainTUI(router)
+			b.StopTimer()
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "lines/s")
+		})
+	}
+}
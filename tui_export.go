@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultCopyLines is the number of lines copied when nothing is selected.
+	defaultCopyLines = 100
+	exportTimeFormat = "20060102-150405"
+)
+
+// unsafeFileChars matches the characters of a panel name replaced in file names.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// exportFileName returns the file a panel is saved to at the given time.
+func exportFileName(dir, name string, now time.Time) string {
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.log", unsafeFileChars.ReplaceAllString(name, "_"), now.Format(exportTimeFormat)))
+}
+
+// panelText returns the content of a panel without colors.
+func (t *tuiRouter) panelText(name string) string {
+	view := t.views[name]
+	if view == nil {
+		return ""
+	}
+	return strings.TrimSuffix(view.GetText(true), "\n")
+}
+
+// exportPanels saves the panels to timestamped files in the export directory
+// and returns their paths. It must run on the UI goroutine, or once the TUI stopped.
+func (t *tuiRouter) exportPanels(names []string, now time.Time) ([]string, error) {
+	dir := t.options.ExportDir
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create export directory: %w", err)
+	}
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		path := exportFileName(dir, name, now)
+		text := t.panelText(name)
+		if text != "" {
+			text += "\n"
+		}
+		if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+			return paths, fmt.Errorf("save %s: %w", name, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// exportVisible saves the visible panel, or every panel when all is set.
+func (t *tuiRouter) exportVisible(all bool) {
+	names := []string{t.visibleName()}
+	if all {
+		names = t.order
+	}
+	paths, err := t.exportPanels(names, time.Now())
+	if err != nil {
+		baseLog("export failed: %v", err)
+		return
+	}
+	baseLog("saved %s", strings.Join(paths, ", "))
+}
+
+// copyLines returns the lines copied from the panel name: the current search
+// match, or its last lines.
+func (t *tuiRouter) copyLines(name string) string {
+	if t.search != nil && t.search.name == name && t.search.matches > 0 {
+		return t.views[name].GetRegionText(fmt.Sprintf("%s%d", searchRegionPrefix, t.search.current))
+	}
+	count := t.options.CopyLines
+	if count <= 0 {
+		count = defaultCopyLines
+	}
+	lines := strings.Split(t.panelText(name), "\n")
+	return strings.Join(lines[max(len(lines)-count, 0):], "\n")
+}
+
+// copyVisible copies the selection of the visible panel to the clipboard of
+// the terminal with OSC 52, which works over SSH as well.
+func (t *tuiRouter) copyVisible() {
+	name := t.visibleName()
+	text := t.copyLines(name)
+	if t.screen == nil || text == "" {
+		return
+	}
+	t.screen.SetClipboard([]byte(text))
+	baseLog("copied %d lines of %s to the clipboard", strings.Count(text, "\n")+1, name)
+}
+
+// exportOnExit saves every panel once the TUI stopped, when configured.
+func (t *tuiRouter) exportOnExit() {
+	if !t.options.ExportOnExit {
+		return
+	}
+	// Output still waiting for a frame is part of the panels.
+	t.flush()
+	paths, err := t.exportPanels(t.order, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to save panels: %v\n", err) //nolint:errcheck
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Panels saved to %s\n", strings.Join(paths, ", ")) //nolint:errcheck
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestExportFileName(t *testing.T) {
+	now := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "api", want: "logs/api-20250102-150405.log"},
+		{name: "all logs", want: "logs/all_logs-20250102-150405.log"},
+		{name: "../web:dev", want: "logs/.._web_dev-20250102-150405.log"},
+	}
+	for _, tt := range tests {
+		if got := exportFileName("logs", tt.name, now); got != filepath.FromSlash(tt.want) {
+			t.Errorf("exportFileName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// readExport returns the content of the only file saved for the panel name in dir.
+func readExport(t *testing.T, dir, name string) string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, name+"-*.log"))
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("expected one export of %s in %s, got %v (%v)", name, dir, paths, err)
+	}
+	data, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+	return string(data)
+}
+
+func TestTUIRouterExport(t *testing.T) {
+	origErrorOutput := errorOutput
+	defer func() { errorOutput = origErrorOutput }()
+
+	dir := t.TempDir()
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{ExportDir: dir, CopyLines: 2})
+	errorOutput = router.BaseWriter()
+	write := router.LineWriter("api", nil, "")
+	for _, line := range []string{"\x1b[31mstarting\x1b[0m", "listening", "crashed"} {
+		write(line)
+	}
+	waitForScreen(t, router, screen, "crashed")
+
+	screen.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'e', tcell.ModNone)
+	waitForScreen(t, router, screen, "[gonc] saved")
+	if got, want := readExport(t, dir, "api"), "starting\nlistening\ncrashed\n"; got != want {
+		t.Errorf("exported api = %q, want %q", got, want)
+	}
+
+	screen.InjectKey(tcell.KeyRune, 'y', tcell.ModNone)
+	waitForScreen(t, router, screen, "copied 2 lines of api")
+	if got := string(screen.GetClipboardData()); got != "listening\ncrashed" {
+		t.Errorf("clipboard = %q, want the last 2 lines", got)
+	}
+
+	screen.InjectKey(tcell.KeyRune, '/', tcell.ModNone)
+	for _, r := range "list" {
+		screen.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForScreen(t, router, screen, "/list 1/1")
+	screen.InjectKey(tcell.KeyRune, 'y', tcell.ModNone)
+	waitForScreen(t, router, screen, "copied 1 lines of api")
+	if got := string(screen.GetClipboardData()); got != "listening" {
+		t.Errorf("clipboard = %q, want the search match", got)
+	}
+}
+
+func TestTUIRouterExportOnExit(t *testing.T) {
+	dir := t.TempDir()
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{ExportDir: dir, ExportOnExit: true})
+	waitForScreen(t, router, screen, "api ● waiting")
+	router.LineWriter("api", nil, "")("last words")
+	router.Finish(false)
+
+	if got := readExport(t, dir, "api"); !strings.Contains(got, "last words") {
+		t.Errorf("exported api = %q, want the pending output", got)
+	}
+	readExport(t, dir, basePanelName)
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// helpName is the overlay listing the key bindings.
+const helpName = "help"
+
+// Actions of the TUI, bound to keys and listed in the command palette.
+const (
+	keyRestart    = "restart"
+	keyStop       = "stop"
+	keyStart      = "start"
+	keyKill       = "kill"
+	keyRestartAll = "restartAll"
+	keyZoom       = "zoom"
+	keyLayout     = "layout"
+	keyAllLogs    = "allLogs"
+	keyFilter     = "filter"
+	keyLifecycle  = "lifecycle"
+	keySteps      = "steps"
+	keySave       = "save"
+	keySaveAll    = "saveAll"
+	keyCopy       = "copy"
+	keySearch     = "search"
+	keyNextMatch  = "nextMatch"
+	keyPrevMatch  = "prevMatch"
+	keyFollow     = "follow"
+	keyInput      = "input"
+	keyStats      = "stats"
+	keyHelp       = "help"
+	keyPalette    = "palette"
+	keyQuit       = "quit"
+	keyQuitNow    = "quitNow"
+)
+
+// tuiAction is an action of the TUI with its default key, empty when it is
+// only available from the command palette.
+type tuiAction struct {
+	name        string
+	key         string
+	description string
+}
+
+// tuiActions lists the actions in the order of the help overlay.
+var tuiActions = []tuiAction{
+	{keyHelp, "?", "Show / leave this help"},
+	{keyPalette, ":", "Open the command palette"},
+	{keyZoom, "z", "Maximize the focused panel / restore the layout"},
+	{keyLayout, "l", "Switch to the next layout"},
+	{keyAllLogs, "a", "Show / leave the merged view of all output"},
+	{keyFilter, "F", "Filter the merged view"},
+	{keyLifecycle, "v", "Hide / show the lifecycle messages"},
+	{keySteps, "p", "Show / leave the setup and shutdown steps"},
+	{keySave, "e", "Save the focused panel to a file"},
+	{keySaveAll, "E", "Save every panel to a file"},
+	{keyCopy, "y", "Copy the search match or the last lines to the clipboard"},
+	{keySearch, "/", "Search the focused panel"},
+	{keyNextMatch, "n", "Jump to the next search match"},
+	{keyPrevMatch, "N", "Jump to the previous search match"},
+	{keyFollow, "f", "Pause / resume following the output"},
+	{keyInput, "i", "Type into the focused command's stdin"},
+	{keyStats, "c", "Show / hide the CPU and memory of the commands"},
+	{keyRestart, "r", "Restart the focused command"},
+	{keyStop, "s", "Stop the focused command gracefully"},
+	{keyStart, "t", "Start the focused command again"},
+	{keyKill, "K", "Force kill the focused command"},
+	{keyRestartAll, "R", "Restart all commands"},
+	{keyQuit, "q", "Stop all commands gracefully and quit"},
+	{keyQuitNow, "", "Kill all commands and quit immediately"},
+}
+
+// statusHintActions are the actions whose keys the status bar shows.
+var statusHintActions = []string{keyHelp, keyPalette, keyQuit, keyZoom, keyLayout, keyAllLogs, keySearch, keyFollow, keyRestart, keyStop, keyRestartAll}
+
+// newKeyMap binds every action to its key, applying the keys remapped in the
+// configuration by action name.
+func newKeyMap(remap map[string]string) (map[rune]string, error) {
+	keys := make(map[string]string, len(tuiActions))
+	for _, action := range tuiActions {
+		keys[action.name] = action.key
+	}
+	for name, key := range remap {
+		if _, ok := keys[name]; !ok {
+			return nil, fmt.Errorf("keys: unknown action %q", name)
+		}
+		if key != "" && utf8.RuneCountInString(key) != 1 {
+			return nil, fmt.Errorf("keys.%s: %q is not a single character", name, key)
+		}
+		keys[name] = key
+	}
+	keyMap := make(map[rune]string, len(keys))
+	for _, action := range tuiActions {
+		key := keys[action.name]
+		if key == "" {
+			continue
+		}
+		r, _ := utf8.DecodeRuneInString(key)
+		if other, ok := keyMap[r]; ok {
+			return nil, fmt.Errorf("keys: %q is bound to both %s and %s", key, other, action.name)
+		}
+		keyMap[r] = action.name
+	}
+	return keyMap, nil
+}
+
+// keyMap returns the key bindings, the default ones unless configured.
+func (t *tuiRouter) keyMap() map[rune]string {
+	if t.keys == nil {
+		t.keys, _ = newKeyMap(nil)
+	}
+	return t.keys
+}
+
+// keyFor returns the key bound to an action, or "" when it has none.
+func (t *tuiRouter) keyFor(action string) string {
+	var keys []string
+	for r, name := range t.keyMap() {
+		if name == action {
+			keys = append(keys, string(r))
+		}
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, " ")
+}
+
+// keyHints returns the key bindings shown in the status bar.
+func (t *tuiRouter) keyHints() string {
+	hints := []string{"Tab focus"}
+	for _, name := range statusHintActions {
+		if key := t.keyFor(name); key != "" {
+			hints = append(hints, key+" "+strings.ToLower(tuiActionLabel(name)))
+		}
+	}
+	return tview.Escape(strings.Join(hints, "  "))
+}
+
+// tuiActionLabel returns the short name of an action in the status bar.
+func tuiActionLabel(name string) string {
+	switch name {
+	case keyAllLogs:
+		return "all logs"
+	case keyRestartAll:
+		return "restart all"
+	case keyPalette:
+		return "commands"
+	}
+	return name
+}
+
+// runAction runs an action of the TUI on the focused panel. It must run on
+// the UI goroutine.
+func (t *tuiRouter) runAction(name string) {
+	switch name {
+	case keyRestart:
+		t.sendControl(t.focusedName(), actionRestart, "key pressed in TUI")
+	case keyStop:
+		t.sendControl(t.focusedName(), actionStop, "key pressed in TUI")
+	case keyStart:
+		t.sendControl(t.focusedName(), actionStart, "key pressed in TUI")
+	case keyKill:
+		t.sendControl(t.focusedName(), actionKill, "key pressed in TUI")
+	case keyRestartAll:
+		t.controls.SendAll(actionRestart, "restart all from TUI")
+	case keyZoom:
+		t.toggleZoom()
+	case keyLayout:
+		t.cycleLayout()
+	case keyAllLogs:
+		t.toggleOverlay(allLogsName)
+	case keyFilter:
+		t.startLogFilter()
+	case keyLifecycle:
+		t.toggleLifecycle()
+	case keySteps:
+		t.toggleOverlay(stepsName)
+	case keySave:
+		t.exportVisible(false)
+	case keySaveAll:
+		t.exportVisible(true)
+	case keyCopy:
+		t.copyVisible()
+	case keySearch:
+		t.startSearch()
+	case keyNextMatch:
+		t.nextMatch(1)
+	case keyPrevMatch:
+		t.nextMatch(-1)
+	case keyFollow:
+		t.setPaused(t.visibleName(), !t.paused[t.visibleName()])
+	case keyInput:
+		t.startInput()
+	case keyStats:
+		t.toggleStats()
+	case keyHelp:
+		t.toggleOverlay(helpName)
+	case keyPalette:
+		t.startPalette()
+	case keyQuit:
+		t.confirmQuit()
+	case keyQuitNow:
+		if t.forceQuit != nil {
+			t.forceQuit()
+		}
+	}
+}
+
+// sendControl asks the worker of a main command to act on its process.
+func (t *tuiRouter) sendControl(name, action, reason string) {
+	if name != t.baseName {
+		t.controls.Send(name, action, reason)
+	}
+}
+
+// confirmQuit stops every command gracefully, as the first interrupt does,
+// after a confirmation when some of them are still running.
+func (t *tuiRouter) confirmQuit() {
+	if t.requestStop == nil || t.root == nil {
+		return
+	}
+	running := 0
+	for _, ev := range t.statuses {
+		if ev.Running {
+			running++
+		}
+	}
+	if running == 0 {
+		t.quit()
+		return
+	}
+	input := t.openPrompt(fmt.Sprintf("%d processes still running, stop them and quit? (y/N) ", running))
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		t.closePrompt()
+		if event.Key() == tcell.KeyRune && (event.Rune() == 'y' || event.Rune() == 'Y') {
+			t.quit()
+		}
+		return nil
+	})
+}
+
+// quit stops every command gracefully.
+func (t *tuiRouter) quit() {
+	baseLog("quit requested, stopping all processes...")
+	t.requestStop()
+}
+
+// handleHelpKey closes the help overlay with Esc or q.
+func (t *tuiRouter) handleHelpKey(event *tcell.EventKey) bool {
+	if event.Key() == tcell.KeyEscape || event.Key() == tcell.KeyRune && event.Rune() == 'q' {
+		t.toggleOverlay(helpName)
+		return true
+	}
+	return false
+}
+
+// renderHelp lists the key bindings in the help overlay.
+func (t *tuiRouter) renderHelp() {
+	view := t.views[helpName]
+	if view == nil {
+		return
+	}
+	var b strings.Builder
+	row := func(key, description string) {
+		fmt.Fprintf(&b, " [yellow]%-10s[-] %s\n", tview.Escape(key), description)
+	}
+	row("Tab", "Focus the next panel")
+	row("Shift+Tab", "Focus the previous panel")
+	row("Ctrl+C", "Stop all commands gracefully (twice to force)")
+	for _, action := range tuiActions {
+		key := t.keyFor(action.name)
+		if key == "" {
+			key = ":" + paletteLabel(action.name)
+		}
+		row(key, action.description)
+	}
+	b.WriteString("\n [gray]Esc or q closes the help, the mouse focuses, scrolls and resizes the panels[-]")
+	view.SetText(b.String())
+	view.ScrollToBeginning()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestNewKeyMap(t *testing.T) {
+	keys, err := newKeyMap(nil)
+	if err != nil {
+		t.Fatalf("newKeyMap() error = %v", err)
+	}
+	if keys['r'] != keyRestart || keys['?'] != keyHelp || keys[':'] != keyPalette {
+		t.Errorf("default keys = %v", keys)
+	}
+
+	keys, err = newKeyMap(map[string]string{keyRestart: "x", keyQuit: "q"})
+	if err != nil {
+		t.Fatalf("newKeyMap() error = %v", err)
+	}
+	if keys['x'] != keyRestart || keys['q'] != keyQuit {
+		t.Errorf("remapped keys = %v", keys)
+	}
+	if _, ok := keys['r']; ok {
+		t.Error("the previous key of a remapped action should be free")
+	}
+
+	for _, remap := range []map[string]string{
+		{"explode": "x"},
+		{keyZoom: "zz"},
+		{keyZoom: "r"},
+	} {
+		if _, err := newKeyMap(remap); err == nil {
+			t.Errorf("newKeyMap(%v) expected an error", remap)
+		}
+	}
+}
+
+func TestTUIRouterRemappedKeys(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{Keys: map[string]string{keyHelp: "h", keyRestart: "x"}})
+	controls := newProcessControl([]string{"api"})
+	router.BindControls(controls)
+	waitForScreen(t, router, screen, "h help")
+
+	screen.InjectKey(tcell.KeyRune, 'h', tcell.ModNone)
+	waitForScreen(t, router, screen, "Open the command palette")
+	screen.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	waitForScreenWithout(t, router, screen, "Open the command palette")
+
+	screen.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'x', tcell.ModNone)
+	if req := <-controls.Channel("api"); req.action != actionRestart {
+		t.Errorf("expected a restart request, got %+v", req)
+	}
+}
+
+func TestTUIRouterConfirmQuit(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{})
+	tm := newTerminationManager(nil)
+	defer tm.Shutdown()
+	router.BindTermination(tm)
+	events := make(chan statusEvent, 1)
+	router.WatchStatus(events)
+	events <- statusEvent{Phase: phaseMain, Name: "api", Version: 1, Label: statusRunning, Running: true, PID: 7, StartedAt: time.Now()}
+	waitForScreen(t, router, screen, "pid 7")
+	stop := tm.StopSignals().stop
+
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	waitForScreen(t, router, screen, "1 processes still running, stop them and quit? (y/N)")
+	screen.InjectKey(tcell.KeyRune, 'n', tcell.ModNone)
+	waitForScreenWithout(t, router, screen, "still running")
+	select {
+	case <-stop:
+		t.Fatal("declining must not stop the processes")
+	default:
+	}
+
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	waitForScreen(t, router, screen, "still running")
+	screen.InjectKey(tcell.KeyRune, 'y', tcell.ModNone)
+	select {
+	case <-stop:
+	case <-time.After(2 * time.Second):
+		t.Fatal("confirming should stop the processes gracefully")
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// Layouts arranging the TUI panels.
+const (
+	layoutGrid    = "grid"
+	layoutRows    = "rows"
+	layoutColumns = "columns"
+	layoutTabs    = "tabs"
+	layoutList    = "list"
+	layoutZoom    = "zoom"
+)
+
+// tuiLayouts is the order in which the layout key cycles through the layouts.
+var tuiLayouts = []string{layoutGrid, layoutRows, layoutColumns, layoutTabs, layoutList}
+
+const (
+	listPaneWidth     = 30
+	defaultScrollback = 10000
+)
+
+// tuiOptions holds the TUI settings taken from the configuration.
+type tuiOptions struct {
+	Layout       string
+	Weights      map[string]int
+	Scrollback   int
+	ExportDir    string
+	ExportOnExit bool
+	CopyLines    int
+	StateFile    string
+	Keys         map[string]string
+	Stats        bool
+	StatsPane    bool
+	Theme        tuiTheme
+}
+
+func newTUIOptions(cfg Config) tuiOptions {
+	weights := make(map[string]int, len(cfg.Commands))
+	for _, c := range cfg.Commands {
+		if c.Weight > 0 {
+			weights[c.Name] = c.Weight
+		}
+	}
+	return tuiOptions{
+		Layout:       cfg.TUILayout,
+		Weights:      weights,
+		Scrollback:   cfg.Scrollback,
+		ExportDir:    cfg.ExportDir,
+		ExportOnExit: cfg.ExportOnExit,
+		CopyLines:    cfg.CopyLines,
+		StateFile:    cfg.TUIStateFile,
+		Keys:         cfg.Keys,
+		Stats:        cfg.Stats.Enabled,
+		StatsPane:    cfg.Stats.Pane,
+	}
+}
+
+// maxLines returns the number of lines kept per panel, 0 meaning unlimited.
+func (o tuiOptions) maxLines() int {
+	switch {
+	case o.Scrollback < 0:
+		return 0
+	case o.Scrollback == 0:
+		return defaultScrollback
+	default:
+		return o.Scrollback
+	}
+}
+
+// historyLines returns how many entries are kept to rebuild filtered views.
+func (o tuiOptions) historyLines() int {
+	if o.maxLines() == 0 {
+		return maxPendingLines
+	}
+	return o.maxLines()
+}
+
+// pendingLines returns how many lines a panel buffers between two frames,
+// leaving room in the scrollback for the marker of dropped lines.
+func (o tuiOptions) pendingLines() int {
+	if o.maxLines() == 0 {
+		return maxPendingLines
+	}
+	return max(o.maxLines()-1, 1)
+}
+
+// weight returns the relative size of a panel, 1 unless configured.
+func (o tuiOptions) weight(name string) int {
+	if w := o.Weights[name]; w > 0 {
+		return w
+	}
+	return 1
+}
+
+// nextLayout returns the layout following current in the cycle order.
+func nextLayout(current string) string {
+	for i, layout := range tuiLayouts {
+		if layout == current {
+			return tuiLayouts[(i+1)%len(tuiLayouts)]
+		}
+	}
+	return tuiLayouts[0]
+}
+
+// gridRows splits the panels into the rows of the grid.
+func gridRows(names []string) [][]string {
+	rows, cols := calculateGridDimensions(len(names))
+	out := make([][]string, 0, rows)
+	for r := 0; r < rows && r*cols < len(names); r++ {
+		out = append(out, names[r*cols:min((r+1)*cols, len(names))])
+	}
+	return out
+}
+
+// arrangeGrid places the panels in a square-ish grid. A row is as tall as
+// rowWeight returns for it, by default the largest weight among its panels.
+func arrangeGrid(names []string, views map[string]*tview.TextView, weight func(string) int, rowWeight func(int) int) *tview.Flex {
+	grid := tview.NewFlex().SetDirection(tview.FlexRow)
+	for r, rowNames := range gridRows(names) {
+		row := tview.NewFlex().SetDirection(tview.FlexColumn)
+		height := 1
+		for _, name := range rowNames {
+			w := weight(name)
+			row.AddItem(views[name], 0, w, false)
+			height = max(height, w)
+		}
+		if rowWeight != nil && rowWeight(r) > 0 {
+			height = rowWeight(r)
+		}
+		grid.AddItem(row, 0, height, false)
+	}
+	return grid
+}
+
+// arrangeStack places the panels one after the other in the given tview
+// direction, sized by their weights.
+func arrangeStack(direction int, names []string, views map[string]*tview.TextView, weight func(string) int) *tview.Flex {
+	stack := tview.NewFlex().SetDirection(direction)
+	for _, name := range names {
+		stack.AddItem(views[name], 0, weight(name), false)
+	}
+	return stack
+}
+
+// showsSinglePanel reports whether only the focused panel is visible, so that
+// moving the focus requires rebuilding the layout.
+func (t *tuiRouter) showsSinglePanel() bool {
+	return t.zoomed || t.overlay != "" || t.layout == layoutTabs || t.layout == layoutList
+}
+
+// applyLayout rebuilds the screen for the current layout. It must run on the
+// UI goroutine once the router is initialized.
+func (t *tuiRouter) applyLayout() {
+	if t.root == nil {
+		return
+	}
+	focused := t.views[t.visibleName()]
+	if focused == nil {
+		focused = t.defaultView
+	}
+	t.navigation = nil
+	var content tview.Primitive
+	switch {
+	case t.zoomed || t.overlay != "":
+		content = focused
+	case t.layout == layoutRows:
+		content = arrangeStack(tview.FlexRow, t.order, t.views, t.layoutWeight(layoutRows))
+	case t.layout == layoutColumns:
+		content = arrangeStack(tview.FlexColumn, t.order, t.views, t.layoutWeight(layoutColumns))
+	case t.layout == layoutTabs:
+		t.navigation = t.newNavigation()
+		content = tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(t.navigation, 1, 0, false).
+			AddItem(focused, 0, 1, true)
+	case t.layout == layoutList:
+		t.navigation = t.newNavigation()
+		t.navigation.SetBorder(true).SetTitle(" processes ")
+		content = tview.NewFlex().SetDirection(tview.FlexColumn).
+			AddItem(t.navigation, listPaneWidth, 0, false).
+			AddItem(focused, 0, 1, true)
+	default:
+		content = arrangeGrid(t.order, t.views, t.layoutWeight(layoutGrid), t.gridRowWeight)
+	}
+	t.root.Clear()
+	t.root.AddItem(content, 0, 1, true)
+	if t.showStats && t.statsPane != nil {
+		// One line per command, within the borders.
+		t.root.AddItem(t.statsPane, max(len(t.order)-1, 1)+2, 0, false)
+	}
+	if t.prompt != nil {
+		t.root.AddItem(t.prompt, 1, 0, true)
+	} else {
+		t.root.AddItem(t.statusBar, 1, 0, false)
+	}
+	t.renderNavigation()
+	t.app.SetFocus(focused)
+	t.highlightFocus()
+}
+
+// newNavigation creates the tab bar or the process list.
+func (t *tuiRouter) newNavigation() *tview.TextView {
+	return themedTextView(tview.NewTextView().SetDynamicColors(true).SetWrap(false), t.options.Theme.Text, t.options.Theme.Background)
+}
+
+// renderNavigation fills the tab bar or the process list, if shown.
+func (t *tuiRouter) renderNavigation() {
+	if t.navigation == nil {
+		return
+	}
+	var b strings.Builder
+	for i, name := range t.order {
+		marker := "[gray]●[-]"
+		if ev, ok := t.statuses[name]; ok {
+			marker = fmt.Sprintf("[%s]●[-]", statusColor(ev))
+		}
+		label := tview.Escape(name)
+		if i == t.focused {
+			label = "[black:yellow]" + label + "[-:-]"
+		}
+		if t.layout == layoutTabs {
+			fmt.Fprintf(&b, " %s %s │", marker, label)
+			continue
+		}
+		fmt.Fprintf(&b, "%s %s", marker, label)
+		if ev, ok := t.statuses[name]; ok {
+			fmt.Fprintf(&b, " [gray]%s[-]", ev.Label)
+		}
+		b.WriteString("\n")
+	}
+	t.navigation.SetText(b.String())
+}
+
+// toggleZoom maximizes the focused panel or restores the layout.
+func (t *tuiRouter) toggleZoom() {
+	t.zoomed = !t.zoomed
+	t.applyLayout()
+	t.refreshStatus(time.Now())
+}
+
+// cycleLayout switches to the next layout, leaving the zoom.
+func (t *tuiRouter) cycleLayout() {
+	t.zoomed = false
+	t.layout = nextLayout(t.layout)
+	t.applyLayout()
+	t.refreshStatus(time.Now())
+}
+
+// layoutName describes the current layout in the status bar.
+func (t *tuiRouter) layoutName() string {
+	switch t.overlay {
+	case allLogsName:
+		return "all"
+	case stepsName:
+		return stepsName
+	case helpName:
+		return helpName
+	}
+	if t.zoomed {
+		return layoutZoom
+	}
+	return t.layout
+}
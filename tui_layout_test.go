@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestArrangeGrid(t *testing.T) {
+	names := []string{basePanelName, "api", "web"}
+	views := createPanelViews(names, defaultPanelStyles([]CommandConfig{{Name: "api"}, {Name: "web"}}))
+	weights := map[string]int{"api": 3}
+	weight := func(name string) int { return max(weights[name], 1) }
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	grid := arrangeGrid(names, views, weight, nil)
+	grid.SetRect(0, 0, 80, 40)
+	grid.Draw(screen)
+
+	// Two rows: the base panel and api side by side, then web alone.
+	rects := make(map[string][4]int, len(names))
+	for _, name := range names {
+		x, y, w, h := views[name].GetRect()
+		rects[name] = [4]int{x, y, w, h}
+	}
+	if want := [4]int{0, 0, 20, 30}; rects[basePanelName] != want {
+		t.Errorf("base panel = %v, want %v", rects[basePanelName], want)
+	}
+	if want := [4]int{20, 0, 60, 30}; rects["api"] != want {
+		t.Errorf("api = %v, want %v", rects["api"], want)
+	}
+	if want := [4]int{0, 30, 80, 10}; rects["web"] != want {
+		t.Errorf("web = %v, want %v", rects["web"], want)
+	}
+
+	// rowWeight overrides the height of a row.
+	grid = arrangeGrid(names, views, weight, func(int) int { return 1 })
+	grid.SetRect(0, 0, 80, 40)
+	grid.Draw(screen)
+	if _, _, _, h := views["web"].GetRect(); h != 20 {
+		t.Errorf("web height = %d, want 20 with equal rows", h)
+	}
+}
+
+func TestNextLayout(t *testing.T) {
+	tests := []struct {
+		current string
+		want    string
+	}{
+		{current: layoutGrid, want: layoutRows},
+		{current: layoutTabs, want: layoutList},
+		{current: layoutList, want: layoutGrid},
+		{current: "", want: layoutGrid},
+	}
+	for _, tt := range tests {
+		if got := nextLayout(tt.current); got != tt.want {
+			t.Errorf("nextLayout(%q) = %q, want %q", tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestNewTUIOptions(t *testing.T) {
+	opts := newTUIOptions(Config{
+		TUILayout: layoutTabs,
+		Commands:  []CommandConfig{{Name: "api", Weight: 3}, {Name: "web"}},
+	})
+	if opts.Layout != layoutTabs {
+		t.Errorf("Layout = %q, want %q", opts.Layout, layoutTabs)
+	}
+	if got := opts.weight("api"); got != 3 {
+		t.Errorf("weight(api) = %d, want 3", got)
+	}
+	if got := opts.weight("web"); got != 1 {
+		t.Errorf("weight(web) = %d, want 1", got)
+	}
+}
+
+// screenAfter runs fn on the UI goroutine and returns the redrawn screen.
+func screenAfter(router *tuiRouter, screen tcell.SimulationScreen, fn func()) string {
+	router.app.QueueUpdateDraw(fn)
+	rows := make(chan []string, 1)
+	router.app.QueueUpdate(func() {
+		rows <- screenText(screen)
+	})
+	return strings.Join(<-rows, "\n")
+}
+
+func TestTUIRouterZoom(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api", "web"}, tuiOptions{})
+	waitForScreen(t, router, screen, "web ● waiting")
+
+	key := func(r rune) func() {
+		return func() { router.handleKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone)) }
+	}
+	text := screenAfter(router, screen, func() {
+		router.handleKey(tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone))
+		key('z')()
+	})
+	if !strings.Contains(text, "api ● waiting") || strings.Contains(text, "web ● waiting") {
+		t.Fatalf("expected only the api panel when zoomed:\n%s", text)
+	}
+	if !strings.Contains(text, "| zoom |") {
+		t.Errorf("expected the status bar to show the zoom:\n%s", text)
+	}
+
+	text = screenAfter(router, screen, key('z'))
+	if !strings.Contains(text, "web ● waiting") {
+		t.Errorf("expected every panel after leaving the zoom:\n%s", text)
+	}
+}
+
+func TestTUIRouterNavigationLayouts(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api", "web"}, tuiOptions{Layout: layoutTabs})
+	waitForScreen(t, router, screen, "● goncurrently │ ● api │ ● web │")
+
+	text := screenAfter(router, screen, func() {
+		router.handleKey(tcell.NewEventKey(tcell.KeyBacktab, 0, tcell.ModNone))
+	})
+	if !strings.Contains(text, "web ● waiting") || strings.Contains(text, "api ● waiting") {
+		t.Fatalf("expected the web tab to be shown:\n%s", text)
+	}
+
+	text = screenAfter(router, screen, func() {
+		router.handleKey(tcell.NewEventKey(tcell.KeyRune, 'l', tcell.ModNone))
+	})
+	for _, want := range []string{"processes", "● api waiting", "web ● waiting", "| list |"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("list layout missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestTUIOptionsMaxLines(t *testing.T) {
+	tests := []struct {
+		scrollback int
+		want       int
+	}{
+		{scrollback: 0, want: defaultScrollback},
+		{scrollback: 500, want: 500},
+		{scrollback: -1, want: 0},
+	}
+	for _, tt := range tests {
+		if got := (tuiOptions{Scrollback: tt.scrollback}).maxLines(); got != tt.want {
+			t.Errorf("maxLines() with scrollback %d = %d, want %d", tt.scrollback, got, tt.want)
+		}
+	}
+}
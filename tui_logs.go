@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// allLogsName identifies the merged view of every panel's output.
+const allLogsName = "all logs"
+
+// logEntry is output kept to rebuild the merged view and the base panel
+// when the filters change.
+type logEntry struct {
+	name   string
+	stream string
+	panel  string
+	// plain is the text without escape sequences, used for matching.
+	plain string
+	// text is ready for the merged view: prefixed, escaped and terminated.
+	text      string
+	lifecycle bool
+}
+
+// logFilter selects the entries shown in the merged view. Lifecycle
+// messages are hidden from the base panel as well.
+type logFilter struct {
+	spec          string
+	names         map[string]bool
+	stream        string
+	pattern       *regexp.Regexp
+	hideLifecycle bool
+}
+
+// parseLogFilter reads a filter such as "cmd:api,web stream:stderr timeout|error":
+// cmd: and stream: terms restrict the commands and the stream, the remaining
+// text is a regular expression matched against the output.
+func parseLogFilter(spec string) (*logFilter, error) {
+	f := &logFilter{spec: strings.TrimSpace(spec)}
+	var rest []string
+	for _, term := range strings.Fields(spec) {
+		switch {
+		case strings.HasPrefix(term, "cmd:"):
+			if f.names == nil {
+				f.names = make(map[string]bool)
+			}
+			for _, name := range strings.Split(strings.TrimPrefix(term, "cmd:"), ",") {
+				if name != "" {
+					f.names[name] = true
+				}
+			}
+		case strings.HasPrefix(term, "stream:"):
+			f.stream = strings.TrimPrefix(term, "stream:")
+			if f.stream != streamStdout && f.stream != streamStderr {
+				return nil, fmt.Errorf("unknown stream %q, want stdout or stderr", f.stream)
+			}
+		default:
+			rest = append(rest, term)
+		}
+	}
+	if len(rest) > 0 {
+		pattern, err := regexp.Compile(strings.Join(rest, " "))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		f.pattern = pattern
+	}
+	return f, nil
+}
+
+func (f *logFilter) matches(e logEntry) bool {
+	switch {
+	case e.lifecycle && f.hideLifecycle:
+		return false
+	case len(f.names) > 0 && !f.names[e.name]:
+		return false
+	case f.stream != "" && e.stream != f.stream:
+		return false
+	case f.pattern != nil && !f.pattern.MatchString(e.plain):
+		return false
+	}
+	return true
+}
+
+// showsInPanel reports whether the entry is shown in its own panel.
+func (f *logFilter) showsInPanel(e logEntry) bool {
+	return !e.lifecycle || !f.hideLifecycle
+}
+
+// publish shows an entry in its panel and, when it passes the filter, in the
+// merged view, and keeps it in the history.
+func (t *tuiRouter) publish(entry logEntry, line bufferedLine) {
+	t.logMu.Lock()
+	defer t.logMu.Unlock()
+	t.history.push(entry)
+	if t.filter.showsInPanel(entry) {
+		t.enqueue(entry.panel, line)
+	}
+	if t.filter.matches(entry) {
+		t.enqueue(allLogsName, bufferedLine{text: entry.text})
+	}
+}
+
+// applyLogFilter replaces the filter and rebuilds the merged view and the
+// base panel from the history. It must run on the UI goroutine.
+func (t *tuiRouter) applyLogFilter(filter *logFilter) {
+	t.logMu.Lock()
+	lifecycleChanged := filter.hideLifecycle != t.filter.hideLifecycle
+	t.filter = filter
+	entries := t.history.snapshot()
+	t.buffers[allLogsName].take()
+	if lifecycleChanged {
+		t.buffers[t.baseName].take()
+	}
+	t.logMu.Unlock()
+
+	var merged, base strings.Builder
+	for _, entry := range entries {
+		if filter.matches(entry) {
+			merged.WriteString(entry.text)
+		}
+		if lifecycleChanged && entry.panel == t.baseName && filter.showsInPanel(entry) {
+			base.WriteString(entry.text)
+		}
+	}
+	t.replaceText(allLogsName, merged.String())
+	if lifecycleChanged {
+		t.replaceText(t.baseName, base.String())
+	}
+	t.refreshStatus(time.Now())
+}
+
+// replaceText replaces the content of a panel with escaped text containing ANSI codes.
+func (t *tuiRouter) replaceText(name, text string) {
+	view := t.views[name]
+	if name == t.visibleName() {
+		t.clearSearch()
+	}
+	view.SetText(tview.TranslateANSI(text))
+	if !t.paused[name] {
+		view.ScrollToEnd()
+	}
+}
+
+// toggleOverlay shows the view name in place of the layout, or leaves it.
+func (t *tuiRouter) toggleOverlay(name string) {
+	t.clearSearch()
+	if t.overlay == name {
+		t.overlay = ""
+	} else {
+		t.overlay = name
+	}
+	t.applyLayout()
+	t.refreshStatus(time.Now())
+}
+
+// toggleLifecycle hides or shows goncurrently's own lifecycle messages.
+func (t *tuiRouter) toggleLifecycle() {
+	filter := *t.filter
+	filter.hideLifecycle = !filter.hideLifecycle
+	t.applyLogFilter(&filter)
+}
+
+// startLogFilter opens the merged view with an input line editing its filter.
+func (t *tuiRouter) startLogFilter() {
+	if t.overlay != allLogsName {
+		t.toggleOverlay(allLogsName)
+	}
+	input := t.openPrompt("filter> ")
+	input.SetText(t.filter.spec)
+	input.SetDoneFunc(func(key tcell.Key) {
+		if key != tcell.KeyEnter {
+			t.closePrompt()
+			return
+		}
+		filter, err := parseLogFilter(input.GetText())
+		if err != nil {
+			input.SetLabel("filter (" + err.Error() + ")> ")
+			return
+		}
+		filter.hideLifecycle = t.filter.hideLifecycle
+		t.closePrompt()
+		t.applyLogFilter(filter)
+	})
+}
+
+// allLogsTitle describes the merged view and its filter.
+func (t *tuiRouter) allLogsTitle() string {
+	title := " " + allLogsName + " "
+	if t.filter.spec != "" {
+		title += "[yellow]" + tview.Escape(t.filter.spec) + "[-] "
+	}
+	return title
+}
+
+// visibleName returns the panel shown when a single panel is displayed.
+func (t *tuiRouter) visibleName() string {
+	if t.overlay != "" {
+		return t.overlay
+	}
+	return t.focusedName()
+}
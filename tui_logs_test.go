@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseLogFilter(t *testing.T) {
+	entries := map[string]logEntry{
+		"api out": {name: "api", stream: streamStdout, plain: "listening on :8080"},
+		"api err": {name: "api", stream: streamStderr, plain: "timeout reached"},
+		"web out": {name: "web", stream: streamStdout, plain: "compiled"},
+	}
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{spec: "", want: []string{"api out", "api err", "web out"}},
+		{spec: "cmd:api", want: []string{"api out", "api err"}},
+		{spec: "cmd:web,api stream:stderr", want: []string{"api err"}},
+		{spec: "timeout|compiled", want: []string{"api err", "web out"}},
+		{spec: "cmd:api listening on", want: []string{"api out"}},
+		{spec: "stream:stdin", wantErr: true},
+		{spec: "(", wantErr: true},
+	}
+	for _, tt := range tests {
+		filter, err := parseLogFilter(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLogFilter(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		var got []string
+		for _, key := range []string{"api out", "api err", "web out"} {
+			if filter.matches(entries[key]) {
+				got = append(got, key)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("parseLogFilter(%q) matched %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestLogFilterLifecycle(t *testing.T) {
+	filter := &logFilter{hideLifecycle: true}
+	lifecycle := logEntry{name: basePanelName, lifecycle: true}
+	if filter.matches(lifecycle) || filter.showsInPanel(lifecycle) {
+		t.Error("expected hidden lifecycle messages to be filtered out")
+	}
+	if !filter.showsInPanel(logEntry{name: "api"}) {
+		t.Error("expected command output to stay in its panel")
+	}
+}
+
+func TestTUIRouterAllLogs(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api", "web"}, tuiOptions{})
+	router.LineWriter("api", nil, "")("api ready")
+	router.StreamWriter("web", streamStderr, nil, "[stderr] ")("web failed")
+	fmt.Fprintln(router.LifecycleWriter(), "[api] started") //nolint:errcheck
+	waitForScreen(t, router, screen, "[stderr] web failed")
+
+	screen.InjectKey(tcell.KeyRune, 'a', tcell.ModNone)
+	waitForScreen(t, router, screen, "| all |")
+	text := screenAfter(router, screen, func() {})
+	for _, want := range []string{"[gonc] [api] started", "[api] api ready", "[web stderr] web failed"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("merged view missing %q:\n%s", want, text)
+		}
+	}
+
+	screen.InjectKey(tcell.KeyRune, 'F', tcell.ModNone)
+	for _, r := range "stream:stderr" {
+		screen.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForScreenWithout(t, router, screen, "[api] api ready")
+	waitForScreen(t, router, screen, "[web stderr] web failed")
+
+	screen.InjectKey(tcell.KeyRune, 'a', tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'v', tcell.ModNone)
+	waitForScreenWithout(t, router, screen, "[api] started")
+	screen.InjectKey(tcell.KeyRune, 'v', tcell.ModNone)
+	waitForScreen(t, router, screen, "[gonc] [api] started")
+}
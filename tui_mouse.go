@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// minPanelSize is the smallest width or height a panel is resized to.
+const minPanelSize = 3
+
+// panelDrag is a border between two panels being dragged with the mouse.
+type panelDrag struct {
+	// keys name the weights of the items along the resized axis, and items
+	// are the views giving their current sizes.
+	keys  []string
+	items []*tview.TextView
+	// index is the item before the dragged border.
+	index int
+	// vertical is set when the border moves up and down.
+	vertical bool
+}
+
+// tuiState is what the TUI saves to its state file for the next runs.
+type tuiState struct {
+	Weights map[string]int `json:"weights"`
+}
+
+// loadTUIState reads the panel sizes saved by a previous run, if any.
+func loadTUIState(path string) (map[string]int, error) {
+	weights := make(map[string]int)
+	if path == "" {
+		return weights, nil
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- path comes from the user's config
+	if errors.Is(err, os.ErrNotExist) {
+		return weights, nil
+	}
+	if err != nil {
+		return weights, fmt.Errorf("read TUI state: %w", err)
+	}
+	var state tuiState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return weights, fmt.Errorf("parse TUI state %s: %w", path, err)
+	}
+	for key, w := range state.Weights {
+		if w > 0 {
+			weights[key] = w
+		}
+	}
+	return weights, nil
+}
+
+// saveTUIState writes the panel sizes for the next runs.
+func saveTUIState(path string, weights map[string]int) error {
+	data, err := json.MarshalIndent(tuiState{Weights: weights}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write TUI state: %w", err)
+	}
+	return nil
+}
+
+// layoutWeight returns the panel weights of a layout: the sizes set with the
+// mouse, or the configured weights.
+func (t *tuiRouter) layoutWeight(layout string) func(string) int {
+	return func(name string) int {
+		if w := t.resized[layout+":"+name]; w > 0 {
+			return w
+		}
+		return t.options.weight(name)
+	}
+}
+
+// gridRowWeight returns the height of a grid row set with the mouse, or 0.
+func (t *tuiRouter) gridRowWeight(row int) int {
+	return t.resized[gridRowKey(row)]
+}
+
+func gridRowKey(row int) string {
+	return fmt.Sprintf("%s-row:%d", layoutGrid, row)
+}
+
+// handleMouse implements the mouse bindings: a click focuses a panel, the
+// wheel scrolls it, a double click on its title maximizes it and dragging a
+// border between two panels resizes them.
+func (t *tuiRouter) handleMouse(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction) {
+	x, y := event.Position()
+	switch action {
+	case tview.MouseLeftDown:
+		if t.prompt == nil {
+			if t.drag = t.findBorder(x, y); t.drag != nil {
+				return nil, action
+			}
+		}
+	case tview.MouseMove:
+		if t.drag != nil {
+			t.resizeTo(x, y)
+			return nil, action
+		}
+	case tview.MouseLeftUp:
+		if t.drag != nil {
+			t.drag = nil
+			return nil, action
+		}
+	case tview.MouseLeftClick:
+		if name, ok := t.navigationAt(x, y); ok {
+			t.focusPanel(name)
+			return nil, action
+		}
+		if name := t.panelAt(x, y); name != "" {
+			t.focusPanel(name)
+		}
+	case tview.MouseLeftDoubleClick:
+		if name := t.panelAt(x, y); name != "" && t.overlay == "" {
+			if _, top, _, _ := t.views[name].GetRect(); y == top {
+				t.focusPanel(name)
+				t.toggleZoom()
+				return nil, action
+			}
+		}
+	case tview.MouseScrollUp:
+		if name := t.panelAt(x, y); name != "" {
+			t.setPaused(name, true)
+		}
+	}
+	return event, action
+}
+
+// panelAt returns the visible panel at x, y, if any.
+func (t *tuiRouter) panelAt(x, y int) string {
+	if t.showsSinglePanel() {
+		if view := t.views[t.visibleName()]; view != nil && view.InRect(x, y) {
+			return t.visibleName()
+		}
+		return ""
+	}
+	for _, name := range t.order {
+		if t.views[name].InRect(x, y) {
+			return name
+		}
+	}
+	return ""
+}
+
+// navigationAt returns the panel listed at x, y in the process list.
+func (t *tuiRouter) navigationAt(x, y int) (string, bool) {
+	if t.navigation == nil || t.layout != layoutList || t.zoomed || t.overlay != "" || !t.navigation.InRect(x, y) {
+		return "", false
+	}
+	_, top, _, _ := t.navigation.GetInnerRect()
+	if index := y - top; index >= 0 && index < len(t.order) {
+		return t.order[index], true
+	}
+	return "", false
+}
+
+// focusPanel moves the focus to the panel name.
+func (t *tuiRouter) focusPanel(name string) {
+	for i, candidate := range t.order {
+		if candidate == name && i != t.focused {
+			t.moveFocus(i - t.focused)
+			t.refreshStatus(time.Now())
+			return
+		}
+	}
+}
+
+// findBorder returns the border between two panels at x, y in the grid, rows
+// and columns layouts.
+func (t *tuiRouter) findBorder(x, y int) *panelDrag {
+	if t.zoomed || t.overlay != "" {
+		return nil
+	}
+	switch t.layout {
+	case layoutRows:
+		return t.stackBorder(layoutRows, t.order, x, y, true)
+	case layoutColumns:
+		return t.stackBorder(layoutColumns, t.order, x, y, false)
+	case layoutGrid:
+		rows := gridRows(t.order)
+		for _, names := range rows {
+			if drag := t.stackBorder(layoutGrid, names, x, y, false); drag != nil {
+				return drag
+			}
+		}
+		drag := &panelDrag{vertical: true}
+		for r, names := range rows {
+			drag.keys = append(drag.keys, gridRowKey(r))
+			drag.items = append(drag.items, t.views[names[0]])
+		}
+		return drag.at(x, y)
+	}
+	return nil
+}
+
+// stackBorder returns the border at x, y between two of the panels names,
+// placed one after the other.
+func (t *tuiRouter) stackBorder(layout string, names []string, x, y int, vertical bool) *panelDrag {
+	drag := &panelDrag{vertical: vertical}
+	for _, name := range names {
+		drag.keys = append(drag.keys, layout+":"+name)
+		drag.items = append(drag.items, t.views[name])
+	}
+	return drag.at(x, y)
+}
+
+// at returns the drag with the border found at x, y selected, or nil.
+func (d *panelDrag) at(x, y int) *panelDrag {
+	for i := 0; i+1 < len(d.items); i++ {
+		ax, ay, aw, ah := d.items[i].GetRect()
+		bx, by, _, _ := d.items[i+1].GetRect()
+		if d.vertical && (y == ay+ah-1 || y == by) {
+			d.index = i
+			return d
+		}
+		if !d.vertical && y >= ay && y < ay+ah && (x == ax+aw-1 || x == bx) {
+			d.index = i
+			return d
+		}
+	}
+	return nil
+}
+
+// resizeTo moves the dragged border to x, y. Every item of the axis keeps its
+// current size as weight, so that the others do not move.
+func (t *tuiRouter) resizeTo(x, y int) {
+	d := t.drag
+	sizes := make([]int, len(d.items))
+	for i, item := range d.items {
+		_, _, w, h := item.GetRect()
+		sizes[i] = w
+		if d.vertical {
+			sizes[i] = h
+		}
+	}
+	ax, ay, _, _ := d.items[d.index].GetRect()
+	pos, start := x, ax
+	if d.vertical {
+		pos, start = y, ay
+	}
+	pair := sizes[d.index] + sizes[d.index+1]
+	if pair < 2*minPanelSize {
+		return
+	}
+	sizes[d.index] = min(max(pos-start+1, minPanelSize), pair-minPanelSize)
+	sizes[d.index+1] = pair - sizes[d.index]
+	if t.layout == layoutGrid && !d.vertical {
+		t.pinGridRows()
+	}
+	for i, key := range d.keys {
+		t.resized[key] = max(sizes[i], 1)
+	}
+	t.applyLayout()
+}
+
+// pinGridRows keeps the current heights of the grid rows, which otherwise
+// follow the weights of their panels.
+func (t *tuiRouter) pinGridRows() {
+	for r, names := range gridRows(t.order) {
+		if t.resized[gridRowKey(r)] > 0 {
+			continue
+		}
+		_, _, _, h := t.views[names[0]].GetRect()
+		t.resized[gridRowKey(r)] = max(h, 1)
+	}
+}
+
+// saveState writes the panel sizes to the state file, when configured.
+func (t *tuiRouter) saveState() {
+	if t.options.StateFile == "" || len(t.resized) == 0 {
+		return
+	}
+	if err := saveTUIState(t.options.StateFile, t.resized); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to save the TUI state: %v\n", err) //nolint:errcheck
+	}
+}
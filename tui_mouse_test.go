@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// panelRect returns the position and size of a panel, read on the UI goroutine.
+func panelRect(router *tuiRouter, name string) [4]int {
+	rect := make(chan [4]int, 1)
+	router.app.QueueUpdate(func() {
+		x, y, w, h := router.views[name].GetRect()
+		rect <- [4]int{x, y, w, h}
+	})
+	return <-rect
+}
+
+// mouse runs the mouse handler for an action at x, y on the UI goroutine.
+func mouse(router *tuiRouter, action tview.MouseAction, x, y int) func() {
+	return func() {
+		router.handleMouse(tcell.NewEventMouse(x, y, tcell.ButtonNone, tcell.ModNone), action)
+	}
+}
+
+func TestTUIRouterMouseFocus(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api", "web"}, tuiOptions{})
+	waitForScreen(t, router, screen, "web ● waiting")
+
+	web := panelRect(router, "web")
+	screenAfter(router, screen, mouse(router, tview.MouseLeftClick, web[0]+2, web[1]+2))
+	if got := router.focusedName(); got != "web" {
+		t.Fatalf("focused panel = %q, want web", got)
+	}
+
+	text := screenAfter(router, screen, mouse(router, tview.MouseLeftDoubleClick, web[0]+2, web[1]))
+	if !router.zoomed {
+		t.Fatal("expected a double click on the title to maximize the panel")
+	}
+	if strings.Contains(text, "api ● waiting") {
+		t.Error("the other panels should be hidden while maximized")
+	}
+	screenAfter(router, screen, mouse(router, tview.MouseScrollUp, web[0]+2, web[1]+2))
+	if !router.paused["web"] {
+		t.Error("expected scrolling up to pause the panel")
+	}
+}
+
+func TestTUIRouterMouseResize(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api", "web"}, tuiOptions{Layout: layoutColumns})
+	waitForScreen(t, router, screen, "web ● waiting")
+
+	api := panelRect(router, "api")
+	web := panelRect(router, "web")
+	border := api[0] + api[2] - 1
+	screenAfter(router, screen, mouse(router, tview.MouseLeftDown, border, api[1]+3))
+	screenAfter(router, screen, mouse(router, tview.MouseMove, border+10, api[1]+3))
+	screenAfter(router, screen, mouse(router, tview.MouseLeftUp, border+10, api[1]+3))
+
+	if got := panelRect(router, "api"); got[2] != api[2]+10 {
+		t.Errorf("api width = %d, want %d", got[2], api[2]+10)
+	}
+	if got := panelRect(router, "web"); got[2] != web[2]-10 {
+		t.Errorf("web width = %d, want %d", got[2], web[2]-10)
+	}
+	if router.drag != nil {
+		t.Error("expected the drag to end when the button is released")
+	}
+
+	// Clicks away from a border keep their default behavior.
+	screenAfter(router, screen, mouse(router, tview.MouseLeftDown, api[0]+3, api[1]+3))
+	if router.drag != nil {
+		t.Error("expected no drag inside a panel")
+	}
+}
+
+func TestTUIStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	weights, err := loadTUIState(path)
+	if err != nil || len(weights) != 0 {
+		t.Fatalf("loadTUIState() of a missing file = %v, %v, want no weights", weights, err)
+	}
+
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{Layout: layoutRows, StateFile: path})
+	waitForScreen(t, router, screen, "api ● waiting")
+	base := panelRect(router, basePanelName)
+	border := base[1] + base[3] - 1
+	screenAfter(router, screen, mouse(router, tview.MouseLeftDown, 5, border))
+	screenAfter(router, screen, mouse(router, tview.MouseMove, 5, border-5))
+	router.Finish(false)
+
+	weights, err = loadTUIState(path)
+	if err != nil {
+		t.Fatalf("loadTUIState() error = %v", err)
+	}
+	if weights["rows:"+basePanelName] != base[3]-5 {
+		t.Errorf("saved weights = %v, want the resized height %d", weights, base[3]-5)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadTUIState(path); err == nil {
+		t.Error("expected an error for an invalid state file")
+	}
+	router, screen = newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{Layout: layoutRows, StateFile: path})
+	waitForScreen(t, router, screen, "ignoring the saved panel sizes")
+	router.Finish(false)
+}
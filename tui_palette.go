@@ -0,0 +1,199 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// paletteResults is the number of matches listed by the command palette.
+const paletteResults = 10
+
+// paletteEntry is a command of the palette.
+type paletteEntry struct {
+	label string
+	run   func()
+}
+
+// paletteLabel returns the name of an action in the command palette.
+func paletteLabel(name string) string {
+	switch name {
+	case keyRestartAll:
+		return "restart all"
+	case keyLayout:
+		return "next layout"
+	case keyAllLogs:
+		return "all logs"
+	case keyLifecycle:
+		return "toggle lifecycle"
+	case keyStats:
+		return "toggle stats"
+	case keySave:
+		return "save panel"
+	case keySaveAll:
+		return "save logs"
+	case keyNextMatch:
+		return "next match"
+	case keyPrevMatch:
+		return "previous match"
+	case keyQuit:
+		return "quit gracefully"
+	case keyQuitNow:
+		return "quit immediately"
+	}
+	return name
+}
+
+// paletteEntries returns the commands of the palette: the actions of the
+// keys, the process controls of every command and the layouts.
+func (t *tuiRouter) paletteEntries() []paletteEntry {
+	var entries []paletteEntry
+	for _, name := range t.controls.Names() {
+		for _, action := range []string{actionRestart, actionStop, actionStart, actionKill} {
+			entries = append(entries, paletteEntry{action + " " + name, func() {
+				t.sendControl(name, action, "command palette in TUI")
+			}})
+		}
+	}
+	for _, layout := range tuiLayouts {
+		entries = append(entries, paletteEntry{"layout " + layout, func() { t.setLayout(layout) }})
+	}
+	for _, action := range tuiActions {
+		switch action.name {
+		case keyRestart, keyStop, keyStart, keyKill, keyPalette:
+			continue
+		}
+		entries = append(entries, paletteEntry{paletteLabel(action.name), func() { t.runAction(action.name) }})
+	}
+	return entries
+}
+
+// fuzzyScore matches the characters of query in order in label, ignoring
+// case. Consecutive characters and word starts score higher.
+func fuzzyScore(query, label string) (int, bool) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	labelRunes := []rune(strings.ToLower(label))
+	// Jumping to the next word start finds the better match in most cases,
+	// but may miss one that only the first occurrences give.
+	if score, ok := fuzzyMatch(query, labelRunes, true); ok {
+		return score, true
+	}
+	return fuzzyMatch(query, labelRunes, false)
+}
+
+// fuzzyMatch scores the match of query in label, taking the first occurrence
+// of every character, or its first occurrence at a word start with words.
+func fuzzyMatch(query string, label []rune, words bool) (int, bool) {
+	wordStart := func(i int) bool { return i == 0 || label[i-1] == ' ' }
+	score, pos, last := 0, 0, -2
+	for _, r := range query {
+		next := -1
+		for i := pos; i < len(label); i++ {
+			if label[i] != r {
+				continue
+			}
+			if next < 0 {
+				next = i
+			}
+			if !words || i == last+1 || wordStart(i) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return 0, false
+		}
+		score++
+		if next == last+1 {
+			score += 3
+		}
+		if wordStart(next) {
+			score += 2
+		}
+		last = next
+		pos = next + 1
+	}
+	return score, true
+}
+
+// matchPalette returns the entries matching query, best first.
+func matchPalette(entries []paletteEntry, query string) []paletteEntry {
+	type match struct {
+		entry paletteEntry
+		score int
+	}
+	var matches []match
+	for _, entry := range entries {
+		if score, ok := fuzzyScore(query, entry.label); ok {
+			matches = append(matches, match{entry, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len(matches[i].entry.label) < len(matches[j].entry.label)
+	})
+	result := make([]paletteEntry, 0, len(matches))
+	for _, m := range matches {
+		result = append(result, m.entry)
+	}
+	return result
+}
+
+// startPalette opens the command palette, which runs the best match of the
+// typed text or the command picked in its list.
+func (t *tuiRouter) startPalette() {
+	if t.root == nil {
+		return
+	}
+	entries := t.paletteEntries()
+	run := func(label string) {
+		t.closePrompt()
+		for _, entry := range entries {
+			if entry.label == label {
+				entry.run()
+				return
+			}
+		}
+	}
+	input := t.openPrompt(":")
+	input.SetAutocompleteFunc(func(text string) []string {
+		matches := matchPalette(entries, text)
+		labels := make([]string, 0, paletteResults)
+		for _, entry := range matches[:min(len(matches), paletteResults)] {
+			labels = append(labels, tview.Escape(entry.label))
+		}
+		return labels
+	})
+	input.SetAutocompletedFunc(func(text string, _, source int) bool {
+		if source == tview.AutocompletedNavigate {
+			return false
+		}
+		run(text)
+		return true
+	})
+	input.SetDoneFunc(func(key tcell.Key) {
+		if key != tcell.KeyEnter {
+			t.closePrompt()
+			return
+		}
+		if matches := matchPalette(entries, input.GetText()); len(matches) > 0 && input.GetText() != "" {
+			run(matches[0].label)
+			return
+		}
+		t.closePrompt()
+	})
+}
+
+// setLayout switches to the layout name.
+func (t *tuiRouter) setLayout(name string) {
+	t.zoomed = false
+	t.overlay = ""
+	t.layout = name
+	t.applyLayout()
+	t.refreshStatus(time.Now())
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query string
+		label string
+		match bool
+	}{
+		{query: "rsapi", label: "restart api", match: true},
+		{query: "QUIT", label: "quit gracefully", match: true},
+		{query: "", label: "help", match: true},
+		{query: "api restart", label: "restart api", match: false},
+		{query: "zoomx", label: "zoom", match: false},
+	}
+	for _, tt := range tests {
+		if _, ok := fuzzyScore(tt.query, tt.label); ok != tt.match {
+			t.Errorf("fuzzyScore(%q, %q) match = %t, want %t", tt.query, tt.label, ok, tt.match)
+		}
+	}
+}
+
+func TestMatchPalette(t *testing.T) {
+	entries := []paletteEntry{
+		{label: "restart all"},
+		{label: "restart api"},
+		{label: "quit gracefully"},
+		{label: "quit immediately"},
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "restart api", want: "restart api"},
+		{query: "qi", want: "quit immediately"},
+		{query: "quit", want: "quit gracefully"},
+	}
+	for _, tt := range tests {
+		matches := matchPalette(entries, tt.query)
+		if len(matches) == 0 || matches[0].label != tt.want {
+			t.Errorf("matchPalette(%q) best = %v, want %q", tt.query, matches, tt.want)
+		}
+	}
+}
+
+func TestTUIRouterPalette(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api", "web"}, tuiOptions{})
+	controls := newProcessControl([]string{"api", "web"})
+	router.BindControls(controls)
+	waitForScreen(t, router, screen, "web ● waiting")
+
+	typeText := func(text string) {
+		for _, r := range text {
+			screen.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+		}
+	}
+	screen.InjectKey(tcell.KeyRune, ':', tcell.ModNone)
+	typeText("stop web")
+	waitForScreen(t, router, screen, ":stop web")
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	if req := <-controls.Channel("web"); req.action != actionStop {
+		t.Errorf("expected a stop request for web, got %+v", req)
+	}
+
+	screen.InjectKey(tcell.KeyRune, ':', tcell.ModNone)
+	typeText("layout rows")
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForScreen(t, router, screen, "| rows |")
+}
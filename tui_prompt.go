@@ -0,0 +1,66 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// openPrompt replaces the status bar with an input line and focuses it.
+func (t *tuiRouter) openPrompt(label string) *tview.InputField {
+	t.closePrompt()
+	t.prompt = tview.NewInputField().SetLabel(label)
+	t.root.RemoveItem(t.statusBar)
+	t.root.AddItem(t.prompt, 1, 0, true)
+	t.app.SetFocus(t.prompt)
+	return t.prompt
+}
+
+// closePrompt restores the status bar and the focus of the panel.
+func (t *tuiRouter) closePrompt() {
+	if t.prompt == nil {
+		return
+	}
+	t.root.RemoveItem(t.prompt)
+	t.prompt = nil
+	t.root.AddItem(t.statusBar, 1, 0, false)
+	if view := t.views[t.visibleName()]; view != nil {
+		t.app.SetFocus(view)
+	}
+	t.refreshTitles(time.Now())
+}
+
+// BindInput connects the input line to the stdin of main commands.
+func (t *tuiRouter) BindInput(input *processInput) {
+	t.input = input
+}
+
+// startInput opens an input line whose lines are sent to the stdin of the
+// focused command until it is closed with Esc.
+func (t *tuiRouter) startInput() {
+	name := t.focusedName()
+	if name == t.baseName || t.root == nil {
+		return
+	}
+	if !t.input.accepts(name) {
+		baseLog("[%s] does not accept input (set stdin: true)", name)
+		return
+	}
+	input := t.openPrompt(name + "> ")
+	input.SetDoneFunc(func(key tcell.Key) {
+		if key != tcell.KeyEnter {
+			t.closePrompt()
+			return
+		}
+		text := input.GetText()
+		input.SetText("")
+		// A process that does not read its stdin must not block the UI.
+		go func() {
+			defer recoverPanic(name + " input")
+			if err := t.input.Send(name, text); err != nil {
+				baseLog("input not delivered: %v", err)
+			}
+		}()
+	})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"slices"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// commandStyles returns the panel styles of the commands of a reloaded config.
+func (t *tuiRouter) commandStyles(commands []CommandConfig) map[string]panelAppearance {
+	if t == nil {
+		return nil
+	}
+	styles := defaultPanelStyles(commands)
+	applyTheme(styles, t.options.Theme)
+	return styles
+}
+
+// AddPanel adds the panel of a command started by a reload, or restyles it
+// when it exists. It returns once the panel is ready for the command output.
+func (t *tuiRouter) AddPanel(name string, style panelAppearance) {
+	if t == nil {
+		return
+	}
+	t.queueUpdate(func() {
+		t.logMu.Lock()
+		view, exists := t.views[name]
+		if !exists {
+			view = createPanelView(name, style)
+			if lines := t.options.maxLines(); lines > 0 {
+				view.SetMaxLines(lines + 1)
+			}
+			t.views[name] = view
+			t.buffers[name] = newPanelBuffer(t.options.pendingLines())
+			t.order = append(t.order, name)
+		}
+		t.styles[name] = style
+		t.logMu.Unlock()
+		if exists && style.TitleColor != tcell.ColorDefault {
+			view.SetTitleColor(style.TitleColor)
+		}
+		if _, ok := t.statuses[name]; !ok {
+			t.statuses[name] = statusEvent{Phase: phaseMain, Name: name, Label: statusWaiting}
+		}
+		t.applyLayout()
+		t.refreshStatus(time.Now())
+	})
+}
+
+// RemovePanel removes the panel of a command removed by a reload, once its
+// worker has ended. Its lines stay in the merged view.
+func (t *tuiRouter) RemovePanel(name string) {
+	if t == nil {
+		return
+	}
+	t.queueUpdate(func() {
+		if t.search != nil && t.search.name == name {
+			t.clearSearch()
+		}
+		focused := t.focusedName()
+		t.logMu.Lock()
+		delete(t.views, name)
+		delete(t.buffers, name)
+		delete(t.styles, name)
+		t.order = slices.DeleteFunc(t.order, func(n string) bool { return n == name })
+		t.logMu.Unlock()
+		delete(t.statuses, name)
+		delete(t.usage, name)
+		delete(t.paused, name)
+		t.sizeMu.Lock()
+		delete(t.sizes, name)
+		t.sizeMu.Unlock()
+		if index := slices.Index(t.order, focused); index >= 0 {
+			t.focused = index
+		} else {
+			t.focused = min(t.focused, len(t.order)-1)
+		}
+		t.applyLayout()
+		t.refreshStatus(time.Now())
+	})
+}
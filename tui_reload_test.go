@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTUIRouterAddAndRemovePanel(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api", "web"}, tuiOptions{})
+	waitForScreen(t, router, screen, "web ● waiting")
+
+	styles := router.commandStyles([]CommandConfig{{Name: "api"}, {Name: "worker"}})
+	router.AddPanel("worker", styles["worker"])
+	router.StreamWriter("worker", streamStdout, nil, "")("job done")
+	waitForScreen(t, router, screen, "worker ● waiting")
+	waitForScreen(t, router, screen, "job done")
+
+	screenAfter(router, screen, func() { router.moveFocus(1) })
+	router.RemovePanel("api")
+	waitForScreenWithout(t, router, screen, "api ● waiting")
+
+	order := make(chan []string, 1)
+	router.app.QueueUpdate(func() { order <- append([]string(nil), router.order...) })
+	if got, want := <-order, []string{basePanelName, "web", "worker"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+	focused := make(chan string, 1)
+	router.app.QueueUpdate(func() { focused <- router.focusedName() })
+	if got := <-focused; got != basePanelName && got != "web" {
+		t.Errorf("focus moved to %q after removing the focused panel", got)
+	}
+}
+
+func TestTUIRouterPanelsAfterStop(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{})
+	waitForScreen(t, router, screen, "api ● waiting")
+	router.Stop()
+	<-router.runDone
+
+	// A reload after the TUI has closed must not wait for it.
+	done := make(chan struct{})
+	go func() {
+		router.AddPanel("worker", panelAppearance{})
+		router.RemovePanel("api")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("AddPanel and RemovePanel hung once the TUI had stopped")
+	}
+}
+
+func TestNilTUIRouterPanels(t *testing.T) {
+	var router *tuiRouter
+	router.AddPanel("api", panelAppearance{})
+	router.RemovePanel("api")
+	if styles := router.commandStyles([]CommandConfig{{Name: "api"}}); styles != nil {
+		t.Errorf("commandStyles() = %v without a TUI", styles)
+	}
+}
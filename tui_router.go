@@ -6,6 +6,8 @@ import (
 	"math"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/gdamore/tcell/v2"
@@ -13,17 +15,68 @@ import (
 )
 
 type tuiRouter struct {
-	app         *tview.Application
-	baseName    string
-	views       map[string]*tview.TextView
-	defaultView *tview.TextView
-	stopOnce    sync.Once
-	runDone     chan struct{}
-	runErr      error
+	app          *tview.Application
+	baseName     string
+	views        map[string]*tview.TextView
+	styles       map[string]panelAppearance
+	defaultView  *tview.TextView
+	order        []string
+	focused      int
+	controls     *processControl
+	statusBar    *tview.TextView
+	statuses     map[string]statusEvent
+	options      tuiOptions
+	layout       string
+	zoomed       bool
+	root         *tview.Flex
+	navigation   *tview.TextView
+	paused       map[string]bool
+	search       *panelSearch
+	prompt       *tview.InputField
+	input        *processInput
+	buffers      map[string]*panelBuffer
+	dirty        atomic.Bool
+	logMu        sync.Mutex
+	history      ring[logEntry]
+	filter       *logFilter
+	overlay      string
+	stepMu       sync.Mutex
+	steps        []*tuiStep
+	stepCursor   int
+	stepFollow   bool
+	stepsDirty   atomic.Bool
+	stepsRunning atomic.Bool
+	resized      map[string]int
+	drag         *panelDrag
+	keys         map[rune]string
+	statsPane    *tview.TextView
+	showStats    bool
+	usage        map[string]*ring[processUsage]
+	interrupt    func()
+	requestStop  func()
+	forceQuit    func()
+	screen       tcell.Screen
+	finished     chan struct{}
+	workers      sync.WaitGroup
+	sizeMu       sync.Mutex
+	sizes        map[string][2]int
+	flushQueued  atomic.Bool
+	stopOnce     sync.Once
+	runDone      chan struct{}
+	runErr       error
 }
 
-func newTUIRouter(baseName string, commandNames []string, styles map[string]panelAppearance) (*tuiRouter, error) {
+func newTUIRouter(baseName string, commandNames []string, styles map[string]panelAppearance, opts tuiOptions) (*tuiRouter, error) {
+	return newTUIRouterWithScreen(baseName, commandNames, styles, opts, nil)
+}
+
+// newTUIRouterWithScreen builds and runs the TUI on the given screen, or on
+// the terminal when screen is nil.
+func newTUIRouterWithScreen(baseName string, commandNames []string, styles map[string]panelAppearance, opts tuiOptions, screen tcell.Screen) (*tuiRouter, error) {
 	app := tview.NewApplication()
+	if screen != nil {
+		app.SetScreen(screen)
+	}
 	sectionNames := make([]string, 0, len(commandNames)+1)
 	sectionNames = append(sectionNames, baseName)
 	sectionNames = append(sectionNames, commandNames...)
@@ -40,52 +93,114 @@ func newTUIRouter(baseName string, commandNames []string, styles map[string]pane
 		unique = append(unique, name)
 	}
 	sectionNames = unique
-	layout, views := buildTUILayout(sectionNames, styles)
+	views := createPanelViews(sectionNames, styles)
+	views[allLogsName] = createPanelView(allLogsName, styles[basePanelName])
+	buffers := make(map[string]*panelBuffer, len(views))
+	for name, view := range views {
+		if lines := opts.maxLines(); lines > 0 {
+			// One more line for the empty one following the last newline.
+			view.SetMaxLines(lines + 1)
+		}
+		buffers[name] = newPanelBuffer(opts.pendingLines())
+	}
+	views[stepsName] = createPanelView(stepsName, styles[basePanelName])
+	views[helpName] = createPanelView(helpName, styles[basePanelName])
+	keys, err := newKeyMap(opts.Keys)
+	if err != nil {
+		return nil, err
+	}
+	// The state file is a cache: a broken one only loses the saved sizes.
+	resized, stateErr := loadTUIState(opts.StateFile)
+	viewStyles := make(map[string]panelAppearance, len(views))
+	for name := range views {
+		style, ok := styles[name]
+		if !ok {
+			style = styles[basePanelName]
+		}
+		viewStyles[name] = style
+	}
 
 	defaultView := views[sectionNames[0]]
 	if _, ok := views[baseName]; !ok {
 		baseName = sectionNames[0]
 	}
 
+	theme := opts.Theme
+	statusBackground := theme.StatusBackground
+	if statusBackground == tcell.ColorDefault {
+		statusBackground = theme.Background
+	}
+	statusBar := themedTextView(tview.NewTextView().SetDynamicColors(true), theme.Status, statusBackground)
+	root := tview.NewFlex().SetDirection(tview.FlexRow)
+	if theme.Background != tcell.ColorDefault {
+		root.SetBackgroundColor(theme.Background)
+	}
 	t := &tuiRouter{
 		app:         app,
 		baseName:    baseName,
 		views:       views,
+		styles:      viewStyles,
 		defaultView: defaultView,
+		order:       sectionNames,
+		statusBar:   statusBar,
+		statuses:    make(map[string]statusEvent, len(commandNames)),
+		options:     opts,
+		layout:      opts.Layout,
+		root:        root,
+		paused:      make(map[string]bool, len(sectionNames)),
+		buffers:     buffers,
+		sizes:       make(map[string][2]int, len(views)),
+		history:     ring[logEntry]{limit: opts.historyLines()},
+		filter:      &logFilter{},
+		stepFollow:  true,
+		resized:     resized,
+		keys:        keys,
+		usage:       make(map[string]*ring[processUsage], len(commandNames)),
 		runDone:     make(chan struct{}),
 	}
-
-	app.SetRoot(layout, true)
-	if focusView, ok := views[baseName]; ok {
-		app.SetFocus(focusView)
+	if t.layout == layoutZoom || t.layout == "" {
+		t.zoomed = t.layout == layoutZoom
+		t.layout = layoutGrid
 	}
+	for _, name := range commandNames {
+		t.statuses[name] = statusEvent{Phase: phaseMain, Name: name, Label: statusWaiting}
+	}
+	if opts.Stats {
+		t.statsPane = newStatsPane(theme)
+		t.showStats = opts.StatsPane
+		t.renderStats()
+	}
+	t.renderHelp()
+	app.SetRoot(t.root, true)
+	t.applyLayout()
+	t.refreshStatus(time.Now())
+	app.SetInputCapture(t.handleKey)
+	app.EnableMouse(true)
+	app.SetMouseCapture(t.handleMouse)
+	app.SetAfterDrawFunc(func(screen tcell.Screen) {
+		t.screen = screen
+		t.recordPanelSizes()
+	})
 
 	go func() {
+		defer recoverPanic("TUI")
+		defer close(t.runDone)
 		t.runErr = app.Run()
-		close(t.runDone)
 	}()
+	go t.renderFrames()
+	if stateErr != nil {
+		fmt.Fprintf(t.BaseWriter(), "ignoring the saved panel sizes: %v\n", stateErr) //nolint:errcheck
+	}
 
 	return t, nil
 }
 
-func buildTUILayout(sectionNames []string, styles map[string]panelAppearance) (*tview.Flex, map[string]*tview.TextView) {
-	rows, cols := calculateGridDimensions(len(sectionNames))
-	rootFlex := tview.NewFlex().SetDirection(tview.FlexRow)
-	rowFlexes := make([]*tview.Flex, rows)
-	for r := 0; r < rows; r++ {
-		rowFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
-		rootFlex.AddItem(rowFlex, 0, 1, false)
-		rowFlexes[r] = rowFlex
-	}
-
+func createPanelViews(sectionNames []string, styles map[string]panelAppearance) map[string]*tview.TextView {
 	views := make(map[string]*tview.TextView, len(sectionNames))
-	for idx, name := range sectionNames {
-		style := styles[name]
-		view := createPanelView(name, style)
-		rowFlexes[idx/cols].AddItem(view, 0, 1, idx == 0)
-		views[name] = view
+	for _, name := range sectionNames {
+		views[name] = createPanelView(name, styles[name])
 	}
-	return rootFlex, views
+	return views
 }
 
 func calculateGridDimensions(total int) (rows int, cols int) {
@@ -106,6 +221,7 @@ func calculateGridDimensions(total int) (rows int, cols int) {
 func createPanelView(name string, style panelAppearance) *tview.TextView {
 	textView := tview.NewTextView()
 	textView.SetDynamicColors(true)
+	textView.SetRegions(true)
 	textView.SetBorder(true)
 	textView.SetTitle(name)
 	if style.BorderColor != tcell.ColorDefault {
@@ -117,47 +233,324 @@ func createPanelView(name string, style panelAppearance) *tview.TextView {
 	if style.BackgroundColor != tcell.ColorDefault {
 		textView.SetBackgroundColor(style.BackgroundColor)
 	}
+	if style.TextColor != tcell.ColorDefault {
+		textView.SetTextColor(style.TextColor)
+	}
 	textView.SetScrollable(true)
 	textView.SetWrap(true)
 	return textView
 }
 
 func (t *tuiRouter) BaseWriter() io.Writer {
-	view := t.views[t.baseName]
-	if view == nil {
-		view = t.defaultView
-	}
+	return t.baseWriter(false)
+}
+
+// LifecycleWriter receives the lifecycle messages of baseLog, which can be
+// hidden from the base panel.
+func (t *tuiRouter) LifecycleWriter() io.Writer {
+	return t.baseWriter(true)
+}
+
+func (t *tuiRouter) baseWriter(lifecycle bool) io.Writer {
 	return &textViewWriter{
-		app:         t.app,
-		view:        view,
-		prefix:      color.New(color.FgHiCyan).Sprint("[gonc] "),
+		router:      t,
+		name:        t.baseName,
+		writer:      tview.ANSIWriter(t.views[t.baseName]),
+		prefix:      escapeTviewText(color.New(color.FgHiCyan).Sprint("[gonc] ")),
+		lifecycle:   lifecycle,
 		atLineStart: true,
 	}
 }
 
 func (t *tuiRouter) LineWriter(name string, col *color.Color, prefix string) func(string) {
-	view, ok := t.views[name]
-	if !ok || view == nil {
-		view = t.views[t.baseName]
-	}
-	if view == nil {
-		view = t.defaultView
+	return t.StreamWriter(name, streamStdout, col, prefix)
+}
+
+// StreamWriter is LineWriter for a given stream of the command, so that the
+// merged view can be filtered by stream.
+func (t *tuiRouter) StreamWriter(name, stream string, col *color.Color, prefix string) func(string) {
+	// Reloads add panels on the UI goroutine while workers start.
+	t.logMu.Lock()
+	panel := name
+	if _, ok := t.views[panel]; !ok || panel == allLogsName {
+		panel = t.baseName
 	}
-	if view == nil {
-		return func(string) {
-			// no-op: no view available for rendering
+	view := t.views[panel]
+	t.logMu.Unlock()
+	// Lines of commands with their own panel get a console-like prefix in
+	// the merged view.
+	mergedPrefix := prefix
+	if panel != t.baseName {
+		mergedPrefix = fmt.Sprintf("[%s] ", name)
+		if stream == streamStderr {
+			mergedPrefix = fmt.Sprintf("[%s stderr] ", name)
 		}
 	}
-	coloredPrefix := prefix
+	coloredPrefix, coloredMerged := prefix, mergedPrefix
 	if col != nil {
-		coloredPrefix = col.Sprint(prefix)
+		coloredPrefix, coloredMerged = col.Sprint(prefix), col.Sprint(mergedPrefix)
 	}
+	coloredPrefix, coloredMerged = escapeTviewText(coloredPrefix), escapeTviewText(coloredMerged)
+	// The ANSI translator is kept for the whole stream so that colors opened
+	// on one line carry over to the following ones, as on a real terminal.
+	writer := tview.ANSIWriter(view)
 	return func(line string) {
+		text := escapeTviewText(line)
+		t.publish(logEntry{
+			name:   name,
+			stream: stream,
+			panel:  panel,
+			plain:  stripANSI(line),
+			text:   fmt.Sprintf(lineJoinFormat, coloredMerged, text),
+		}, bufferedLine{
+			writer: writer,
+			text:   fmt.Sprintf(lineJoinFormat, coloredPrefix, text),
+		})
+	}
+}
+
+// WatchStatus applies the status events published by the workers to the panel
+// titles and the status bar, refreshing uptimes every second.
+func (t *tuiRouter) WatchStatus(events <-chan statusEvent) {
+	if events == nil {
+		return
+	}
+	go func() {
+		defer recoverPanic("TUI status")
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case ev := <-events:
+				if ev.Phase != phaseMain {
+					t.queueUpdate(func() {
+						t.updateStep(ev)
+						t.renderSteps(time.Now())
+					})
+					continue
+				}
+				t.queueUpdate(func() {
+					if prev, ok := t.statuses[ev.Name]; ok && prev.Version > ev.Version {
+						return
+					}
+					t.statuses[ev.Name] = ev
+					t.recordUsage(ev)
+					t.renderStats()
+					t.refreshStatus(time.Now())
+				})
+			case <-ticker.C:
+				t.queueUpdate(func() {
+					t.refreshStatus(time.Now())
+				})
+			case <-t.runDone:
+				for range events {
+					// Keep draining so that workers never block once the UI is gone.
+				}
+				return
+			}
+		}
+	}()
+}
+
+// refreshStatus redraws the panel titles and the status bar. It must run on the UI goroutine.
+func (t *tuiRouter) refreshStatus(now time.Time) {
+	t.refreshTitles(now)
+	if t.statusBar != nil {
+		hints := t.keyHints()
+		if t.finished != nil {
+			hints = "[green]finished[-] [gray]press q or Enter to exit"
+		}
+		t.statusBar.SetText(fmt.Sprintf(" %s [gray]| %s | %s", statusCounts(t.order, t.statuses), t.layoutName(), hints))
+	}
+	t.renderNavigation()
+}
+
+// refreshTitles redraws the panel titles with the command status, the follow
+// state and the search matches. It must run on the UI goroutine.
+func (t *tuiRouter) refreshTitles(now time.Time) {
+	for _, name := range append(t.order, allLogsName) {
+		view := t.views[name]
+		if view == nil {
+			continue
+		}
+		suffix := t.searchLabel(name)
+		if t.paused[name] {
+			suffix += "[yellow]⏸ paused[-] "
+		}
+		if name == allLogsName {
+			view.SetTitle(t.allLogsTitle() + suffix)
+		} else if ev, ok := t.statuses[name]; ok {
+			view.SetTitle(panelTitle(name, ev, now) + suffix)
+		} else if suffix != "" {
+			view.SetTitle(" " + name + " " + suffix)
+		} else {
+			view.SetTitle(name)
+		}
+	}
+}
+
+// setPaused stops or resumes following the output of a panel.
+func (t *tuiRouter) setPaused(name string, paused bool) {
+	view := t.views[name]
+	if view == nil || t.paused[name] == paused {
+		return
+	}
+	t.paused[name] = paused
+	if !paused {
+		view.ScrollToEnd()
+	}
+	t.refreshTitles(time.Now())
+}
+
+// recordPanelSizes remembers the inner size of every panel after a draw and
+// resizes the pseudo-terminals when a panel changed.
+func (t *tuiRouter) recordPanelSizes() {
+	changed := false
+	t.sizeMu.Lock()
+	for name, view := range t.views {
+		_, _, width, height := view.GetInnerRect()
+		if width <= 0 || height <= 0 || t.sizes[name] == [2]int{width, height} {
+			continue
+		}
+		t.sizes[name] = [2]int{width, height}
+		changed = true
+	}
+	t.sizeMu.Unlock()
+	if changed {
+		go terminals.resizeAll()
+	}
+}
+
+// PanelSize returns the inner size of the panel of name as last drawn.
+func (t *tuiRouter) PanelSize(name string) (cols, rows int) {
+	t.sizeMu.Lock()
+	defer t.sizeMu.Unlock()
+	size := t.sizes[name]
+	return size[0], size[1]
+}
+
+// BindTermination connects the TUI to the termination of the run: Ctrl+C
+// works like an interrupt signal instead of closing the TUI at once, the quit
+// action stops the processes gracefully and quit immediately forces it.
+func (t *tuiRouter) BindTermination(tm *terminationManager) {
+	t.interrupt = tm.Interrupt
+	t.requestStop = tm.RequestStop
+	t.forceQuit = tm.Terminate
+}
+
+// BindControls connects the process control keys to the workers of main commands.
+func (t *tuiRouter) BindControls(controls *processControl) {
+	t.controls = controls
+}
+
+// handleKey implements the TUI keybindings: the keys of tuiActions, as
+// remapped with keys:, run their action, while Tab and Shift+Tab cycle the
+// focused panel and Ctrl+C stops the processes gracefully. Other keys reach
+// the focused panel, pausing it when they scroll up.
+func (t *tuiRouter) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	if t.finished != nil {
+		if event.Key() == tcell.KeyEnter || event.Key() == tcell.KeyCtrlC || event.Key() == tcell.KeyRune && event.Rune() == 'q' {
+			close(t.finished)
+			t.finished = nil
+			return nil
+		}
+	}
+	if event.Key() == tcell.KeyCtrlC && t.interrupt != nil {
+		t.interrupt()
+		return nil
+	}
+	if t.prompt != nil {
+		return event
+	}
+	if t.overlay == stepsName && t.handleStepKey(event) {
+		return nil
+	}
+	if t.overlay == helpName && t.handleHelpKey(event) {
+		return nil
+	}
+	switch event.Key() {
+	case tcell.KeyEscape:
+		if t.search == nil {
+			return event
+		}
+		t.clearSearch()
+		return nil
+	case tcell.KeyUp, tcell.KeyPgUp, tcell.KeyHome, tcell.KeyCtrlB:
+		t.setPaused(t.visibleName(), true)
+		return event
+	case tcell.KeyEnd:
+		t.setPaused(t.visibleName(), false)
+		return event
+	case tcell.KeyTab:
+		t.moveFocus(1)
+		return nil
+	case tcell.KeyBacktab:
+		t.moveFocus(-1)
+		return nil
+	case tcell.KeyRune:
+	default:
+		return event
+	}
+	if action, ok := t.keyMap()[event.Rune()]; ok {
+		t.runAction(action)
+		return nil
+	}
+	// The scrolling keys of the panels pause following the output.
+	switch event.Rune() {
+	case 'k', 'g':
+		t.setPaused(t.visibleName(), true)
+	case 'G':
+		t.setPaused(t.visibleName(), false)
+	}
+	return event
+}
+
+// moveFocus shifts the focus by delta panels, wrapping around.
+func (t *tuiRouter) moveFocus(delta int) {
+	if len(t.order) == 0 {
+		return
+	}
+	t.clearSearch()
+	t.focused = (t.focused + delta + len(t.order)) % len(t.order)
+	if t.showsSinglePanel() {
+		t.applyLayout()
+		return
+	}
+	if view := t.views[t.order[t.focused]]; view != nil {
+		t.app.SetFocus(view)
+	}
+	t.highlightFocus()
+}
+
+func (t *tuiRouter) focusedName() string {
+	if t.focused < 0 || t.focused >= len(t.order) {
+		return ""
+	}
+	return t.order[t.focused]
+}
+
+// queueUpdate runs f on the UI goroutine and redraws, waiting for it unless
+// the TUI stops first. It reports whether f ran. tview never runs an update
+// queued after its event loop has ended, so the queueing itself must not hold
+// the caller once the TUI is gone.
+func (t *tuiRouter) queueUpdate(f func()) bool {
+	select {
+	case <-t.runDone:
+		return false
+	default:
+	}
+	done := make(chan struct{})
+	go func() {
 		t.app.QueueUpdateDraw(func() {
-			writer := tview.ANSIWriter(view)
-			fmt.Fprintf(writer, lineJoinFormat, coloredPrefix, line) //nolint:errcheck
-			view.ScrollToEnd()
+			f()
+			close(done)
 		})
+	}()
+	select {
+	case <-done:
+		return true
+	case <-t.runDone:
+		return false
 	}
 }
 
@@ -169,49 +562,96 @@ func (t *tuiRouter) Stop() {
 	})
 }
 
-func (t *tuiRouter) Add() {
+// Close stops the TUI and waits for the terminal to be restored.
+func (t *tuiRouter) Close() {
+	t.Stop()
+	select {
+	case <-t.runDone:
+	case <-time.After(time.Second):
+	}
+}
 
+func (t *tuiRouter) Add() {
+	t.workers.Add(1)
 }
 
 func (t *tuiRouter) Done() {
-
+	t.workers.Done()
 }
 
+// Wait returns once every worker is done, or the TUI was closed.
 func (t *tuiRouter) Wait() {
+	done := make(chan struct{})
+	go func() {
+		t.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-t.runDone:
+	}
+}
+
+// Finish closes the TUI at the end of the run. With keepOpen it stays open,
+// showing the final state, until the user leaves it.
+func (t *tuiRouter) Finish(keepOpen bool) {
+	if keepOpen {
+		done := make(chan struct{})
+		if t.queueUpdate(func() {
+			t.finished = done
+			t.refreshStatus(time.Now())
+		}) {
+			select {
+			case <-done:
+			case <-t.runDone:
+			}
+		}
+	}
+	t.Stop()
 	<-t.runDone
+	t.exportOnExit()
+	t.saveState()
 }
 
+// textViewWriter buffers arbitrary writes for a panel, prefixing every line.
 type textViewWriter struct {
-	app         *tview.Application
-	view        *tview.TextView
+	router      *tuiRouter
+	name        string
+	writer      io.Writer
 	prefix      string
+	lifecycle   bool
+	mu          sync.Mutex
 	atLineStart bool
 }
 
 func (w *textViewWriter) Write(p []byte) (int, error) {
-	if w == nil || w.view == nil {
+	if w == nil || w.router == nil {
 		return len(p), nil
 	}
-	text := string(p)
-	w.app.QueueUpdateDraw(func() {
-		tvWriter := tview.ANSIWriter(w.view)
-		remaining := text
-		for len(remaining) > 0 {
-			newlineIdx := strings.IndexByte(remaining, '\n')
-			if w.atLineStart && w.prefix != "" {
-				fmt.Fprint(tvWriter, w.prefix) //nolint:errcheck
-			}
-			if newlineIdx == -1 {
-				fmt.Fprint(tvWriter, remaining) //nolint:errcheck
-				w.atLineStart = false
-				break
-			}
-			fmt.Fprint(tvWriter, remaining[:newlineIdx]) //nolint:errcheck
-			fmt.Fprint(tvWriter, "\n")                   //nolint:errcheck
-			w.atLineStart = true
-			remaining = remaining[newlineIdx+1:]
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var b strings.Builder
+	remaining := escapeTviewText(string(p))
+	for len(remaining) > 0 {
+		if w.atLineStart {
+			b.WriteString(w.prefix)
 		}
-		w.view.ScrollToEnd()
-	})
+		newlineIdx := strings.IndexByte(remaining, '\n')
+		if newlineIdx == -1 {
+			b.WriteString(remaining)
+			w.atLineStart = false
+			break
+		}
+		b.WriteString(remaining[:newlineIdx+1])
+		w.atLineStart = true
+		remaining = remaining[newlineIdx+1:]
+	}
+	w.router.publish(logEntry{
+		name:      w.name,
+		panel:     w.name,
+		plain:     stripANSI(string(p)),
+		text:      b.String(),
+		lifecycle: w.lifecycle,
+	}, bufferedLine{writer: w.writer, text: b.String()})
 	return len(p), nil
 }
@@ -1,7 +1,13 @@
 package main
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
 )
 
 func TestCalculateGridDimensions(t *testing.T) {
@@ -88,3 +94,176 @@ func TestCreatePanelView(t *testing.T) {
 		t.Fatal("createPanelView() for basePanelName returned nil")
 	}
 }
+
+func TestTUIRouterHandleKey(t *testing.T) {
+	names := []string{basePanelName, "api", "web"}
+	views := createPanelViews(names, defaultPanelStyles([]CommandConfig{{Name: "api"}, {Name: "web"}}))
+	controls := newProcessControl([]string{"api", "web"})
+	router := &tuiRouter{
+		app:      tview.NewApplication(),
+		baseName: basePanelName,
+		views:    views,
+		order:    names,
+		controls: controls,
+	}
+
+	if got := router.handleKey(tcell.NewEventKey(tcell.KeyRune, 'r', tcell.ModNone)); got != nil {
+		t.Error("expected control key to be consumed")
+	}
+	select {
+	case req := <-controls.Channel("api"):
+		t.Fatalf("base panel must not dispatch requests, got %+v", req)
+	default:
+	}
+
+	router.handleKey(tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone))
+	if router.focusedName() != "api" {
+		t.Fatalf("expected focus on api, got %q", router.focusedName())
+	}
+	router.handleKey(tcell.NewEventKey(tcell.KeyRune, 's', tcell.ModNone))
+	if req := <-controls.Channel("api"); req.action != actionStop {
+		t.Errorf("expected stop request, got %+v", req)
+	}
+
+	router.handleKey(tcell.NewEventKey(tcell.KeyBacktab, 0, tcell.ModNone))
+	router.handleKey(tcell.NewEventKey(tcell.KeyBacktab, 0, tcell.ModNone))
+	if router.focusedName() != "web" {
+		t.Errorf("expected focus to wrap around to web, got %q", router.focusedName())
+	}
+
+	router.handleKey(tcell.NewEventKey(tcell.KeyRune, 'R', tcell.ModNone))
+	for _, name := range []string{"api", "web"} {
+		if req := <-controls.Channel(name); req.action != actionRestart {
+			t.Errorf("%s: expected restart request, got %+v", name, req)
+		}
+	}
+
+	if got := router.handleKey(tcell.NewEventKey(tcell.KeyRune, 'j', tcell.ModNone)); got == nil {
+		t.Error("expected unrelated keys to reach the focused panel")
+	}
+}
+
+func newSimulatedTUIRouter(t testing.TB, commandNames []string, opts tuiOptions) (*tuiRouter, tcell.SimulationScreen) {
+	t.Helper()
+	screen := tcell.NewSimulationScreen("UTF-8")
+	commands := make([]CommandConfig, 0, len(commandNames))
+	for _, name := range commandNames {
+		commands = append(commands, CommandConfig{Name: name})
+	}
+	router, err := newTUIRouterWithScreen(basePanelName, commandNames, defaultPanelStyles(commands), opts, screen)
+	if err != nil {
+		t.Fatalf("newTUIRouterWithScreen() error = %v", err)
+	}
+	screen.SetSize(120, 30)
+	t.Cleanup(router.Stop)
+	return router, screen
+}
+
+// screenText returns the simulated screen content as one string per row.
+func screenText(screen tcell.SimulationScreen) []string {
+	cells, width, height := screen.GetContents()
+	rows := make([]string, 0, height)
+	for y := 0; y < height; y++ {
+		var b strings.Builder
+		for x := 0; x < width; x++ {
+			runes := cells[y*width+x].Runes
+			if len(runes) == 0 {
+				b.WriteRune(' ')
+				continue
+			}
+			b.WriteString(string(runes))
+		}
+		rows = append(rows, b.String())
+	}
+	return rows
+}
+
+// waitForScreen polls the simulated screen until a row contains want.
+func waitForScreen(t *testing.T, router *tuiRouter, screen tcell.SimulationScreen, want string) {
+	t.Helper()
+	waitForScreenText(t, router, screen, fmt.Sprintf("screen never contained %q", want), func(text string) bool {
+		return strings.Contains(text, want)
+	})
+}
+
+// waitForScreenWithout polls the simulated screen until no row contains unwanted.
+func waitForScreenWithout(t *testing.T, router *tuiRouter, screen tcell.SimulationScreen, unwanted string) {
+	t.Helper()
+	waitForScreenText(t, router, screen, fmt.Sprintf("screen still contained %q", unwanted), func(text string) bool {
+		return !strings.Contains(text, unwanted)
+	})
+}
+
+// waitForScreenText polls the simulated screen until ok accepts its content.
+func waitForScreenText(t *testing.T, router *tuiRouter, screen tcell.SimulationScreen, failure string, ok func(string) bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	var text string
+	for time.Now().Before(deadline) {
+		found := make(chan bool, 1)
+		router.app.QueueUpdateDraw(func() {})
+		router.app.QueueUpdate(func() {
+			text = strings.Join(screenText(screen), "\n")
+			found <- ok(text)
+		})
+		if <-found {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("%s:\n%s", failure, text)
+}
+
+func TestTUIRouterStatusTitles(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{})
+	waitForScreen(t, router, screen, "api ● waiting")
+
+	events := make(chan statusEvent, 4)
+	router.WatchStatus(events)
+	events <- statusEvent{
+		Phase:     phaseMain,
+		Name:      "api",
+		Version:   2,
+		Label:     statusRunning,
+		Running:   true,
+		PID:       4242,
+		StartedAt: time.Now().Add(-65 * time.Second),
+		Restarts:  1,
+	}
+	waitForScreen(t, router, screen, "api ● running pid 4242 up 1m05s ↻1")
+	waitForScreen(t, router, screen, "1 running")
+
+	// Stale events must not override newer ones.
+	events <- statusEvent{Phase: phaseMain, Name: "api", Version: 1, Label: statusWaiting}
+	events <- statusEvent{Phase: phaseMain, Name: "api", Version: 3, Label: "exited(1)"}
+	waitForScreen(t, router, screen, "api ● exited(1)")
+	waitForScreen(t, router, screen, "1 exited")
+}
+
+func TestTUIRouterStatusAfterStop(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{})
+	waitForScreen(t, router, screen, "api ● waiting")
+	events := make(chan statusEvent)
+	router.WatchStatus(events)
+
+	// Workers publishing while and after the UI stops must never block.
+	for i := 0; i < 2*statusEventBuffer; i++ {
+		if i == 10 {
+			router.Stop()
+		}
+		select {
+		case events <- statusEvent{Phase: phaseMain, Name: "api", Version: uint64(i)}:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("status event %d was not drained", i)
+		}
+	}
+}
+
+func TestTUIRouterPanelSize(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{Layout: layoutColumns})
+	waitForScreen(t, router, screen, "api ● waiting")
+	// Two columns side by side on a 120x30 screen, minus borders and the status bar.
+	if cols, rows := router.PanelSize("api"); cols != 58 || rows != 27 {
+		t.Errorf("PanelSize(api) = %dx%d, want 58x27", cols, rows)
+	}
+}
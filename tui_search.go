@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const searchRegionPrefix = "gonc-match-"
+
+// searchRegionPattern matches the region tags added around matching lines.
+var searchRegionPattern = regexp.MustCompile(`\["` + searchRegionPrefix + `\d+"\]|\[""\]`)
+
+// panelSearch is the incremental search running in a panel.
+type panelSearch struct {
+	name    string
+	query   string
+	matches int
+	current int
+}
+
+// startSearch opens the search input line for the focused panel.
+func (t *tuiRouter) startSearch() {
+	t.clearSearch()
+	name := t.visibleName()
+	if t.views[name] == nil || name == stepsName || name == helpName || t.root == nil {
+		return
+	}
+	t.search = &panelSearch{name: name}
+	input := t.openPrompt("/")
+	input.SetChangedFunc(t.runSearch)
+	input.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			t.clearSearch()
+			return
+		}
+		// Closing the input keeps the matches for n and N.
+		t.closePrompt()
+	})
+}
+
+// clearSearch ends the search and removes the match highlighting.
+func (t *tuiRouter) clearSearch() {
+	if t.search == nil {
+		return
+	}
+	t.closePrompt()
+	if view := t.views[t.search.name]; view != nil {
+		view.SetText(searchRegionPattern.ReplaceAllString(view.GetText(false), ""))
+		view.Highlight()
+	}
+	t.search = nil
+	t.refreshTitles(time.Now())
+}
+
+// runSearch marks the lines of the searched panel containing query, ignoring
+// case, and jumps to the last match.
+func (t *tuiRouter) runSearch(query string) {
+	if t.search == nil {
+		return
+	}
+	view := t.views[t.search.name]
+	t.search.query = query
+	raw := strings.Split(searchRegionPattern.ReplaceAllString(view.GetText(false), ""), "\n")
+	plain := strings.Split(view.GetText(true), "\n")
+	matches := 0
+	if query != "" && len(raw) == len(plain) {
+		needle := strings.ToLower(query)
+		for i, line := range plain {
+			if strings.Contains(strings.ToLower(line), needle) {
+				raw[i] = fmt.Sprintf(`["%s%d"]%s[""]`, searchRegionPrefix, matches, raw[i])
+				matches++
+			}
+		}
+	}
+	view.SetText(strings.Join(raw, "\n"))
+	t.search.matches = matches
+	t.search.current = matches - 1
+	t.showMatch()
+}
+
+// nextMatch moves to the following (delta 1) or previous (delta -1) match.
+func (t *tuiRouter) nextMatch(delta int) {
+	if t.search == nil || t.search.matches == 0 {
+		return
+	}
+	t.search.current = (t.search.current + delta + t.search.matches) % t.search.matches
+	t.showMatch()
+}
+
+// showMatch highlights the current match and pauses the panel on it.
+func (t *tuiRouter) showMatch() {
+	view := t.views[t.search.name]
+	if t.search.matches == 0 {
+		view.Highlight()
+	} else {
+		view.Highlight(fmt.Sprintf("%s%d", searchRegionPrefix, t.search.current)).ScrollToHighlight()
+		t.paused[t.search.name] = true
+	}
+	t.refreshTitles(time.Now())
+}
+
+// searchLabel describes the search running in the panel name, if any.
+func (t *tuiRouter) searchLabel(name string) string {
+	if t.search == nil || t.search.name != name || t.search.query == "" {
+		return ""
+	}
+	if t.search.matches == 0 {
+		return fmt.Sprintf("[red]/%s no match[-] ", tview.Escape(t.search.query))
+	}
+	return fmt.Sprintf("[yellow]/%s %d/%d[-] ", tview.Escape(t.search.query), t.search.current+1, t.search.matches)
+}
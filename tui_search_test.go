@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestTUIRouterSearch(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{})
+	write := router.LineWriter("api", nil, "[api] ")
+	for _, line := range []string{"starting", "error one", "ok", "Error two"} {
+		write(line)
+	}
+	waitForScreen(t, router, screen, "[api] Error two")
+
+	screen.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, '/', tcell.ModNone)
+	for _, r := range "err" {
+		screen.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+	waitForScreen(t, router, screen, "/err 2/2")
+	waitForScreen(t, router, screen, "⏸ paused")
+
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'n', tcell.ModNone)
+	waitForScreen(t, router, screen, "/err 1/2")
+
+	screen.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	waitForScreenWithout(t, router, screen, "/err")
+	screen.InjectKey(tcell.KeyRune, 'f', tcell.ModNone)
+	waitForScreenWithout(t, router, screen, "paused")
+	text := screenAfter(router, screen, func() {})
+	if !strings.Contains(text, "[api] error one") {
+		t.Errorf("expected the output to be restored without highlighting:\n%s", text)
+	}
+}
+
+func TestTUIRouterPauseFollow(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{})
+	waitForScreen(t, router, screen, "api ● waiting")
+
+	screen.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyUp, 0, tcell.ModNone)
+	waitForScreen(t, router, screen, "api ● waiting ⏸ paused")
+
+	screen.InjectKey(tcell.KeyEnd, 0, tcell.ModNone)
+	waitForScreenWithout(t, router, screen, "paused")
+}
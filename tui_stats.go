@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// statsHistoryLength is the number of samples drawn in the sparklines.
+const statsHistoryLength = 30
+
+// newStatsPane returns the pane listing the usage of every command.
+func newStatsPane(theme tuiTheme) *tview.TextView {
+	pane := themedTextView(tview.NewTextView().SetDynamicColors(true), theme.Text, theme.Background)
+	pane.SetBorder(true).SetTitle(" stats ")
+	if theme.Border != tcell.ColorDefault {
+		pane.SetBorderColor(theme.Border)
+	}
+	return pane
+}
+
+// recordUsage keeps the samples of a command for its sparklines. It must run
+// on the UI goroutine.
+func (t *tuiRouter) recordUsage(ev statusEvent) {
+	if ev.Usage.Threads == 0 {
+		return
+	}
+	history := t.usage[ev.Name]
+	if history == nil {
+		history = &ring[processUsage]{limit: statsHistoryLength}
+		t.usage[ev.Name] = history
+	}
+	if items := history.snapshot(); len(items) > 0 && !items[len(items)-1].SampledAt.Before(ev.Usage.SampledAt) {
+		return
+	}
+	history.push(ev.Usage)
+}
+
+// toggleStats shows or hides the stats pane.
+func (t *tuiRouter) toggleStats() {
+	if !t.options.Stats {
+		baseLog("process stats are disabled (set stats.enabled: true)")
+		return
+	}
+	t.showStats = !t.showStats
+	t.applyLayout()
+}
+
+// renderStats draws the CPU and memory of every command, with sparklines of
+// their recent samples. It must run on the UI goroutine.
+func (t *tuiRouter) renderStats() {
+	if t.statsPane == nil {
+		return
+	}
+	var b strings.Builder
+	for _, name := range t.order {
+		if name == t.baseName {
+			continue
+		}
+		fmt.Fprintf(&b, " %-16s", tview.Escape(name))
+		ev := t.statuses[name]
+		history := t.usage[name]
+		if !ev.Running || ev.Usage.Threads == 0 || history == nil {
+			b.WriteString(" [gray]-[-]\n")
+			continue
+		}
+		samples := history.snapshot()
+		cpu := make([]float64, len(samples))
+		rss := make([]float64, len(samples))
+		for i, sample := range samples {
+			cpu[i] = sample.CPUPercent
+			rss[i] = float64(sample.RSS)
+		}
+		fmt.Fprintf(&b, " cpu %6.1f%% [green]%-*s[-]  rss %9s [blue]%-*s[-]  threads %d\n",
+			ev.Usage.CPUPercent, statsHistoryLength, sparkline(cpu, 100),
+			formatBytes(ev.Usage.RSS), statsHistoryLength, sparkline(rss, 0),
+			ev.Usage.Threads)
+	}
+	t.statsPane.SetText(strings.TrimSuffix(b.String(), "\n"))
+}
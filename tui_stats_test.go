@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestTUIRouterStatsPane(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api", "web"}, tuiOptions{Stats: true, StatsPane: true})
+	events := make(chan statusEvent, 4)
+	router.WatchStatus(events)
+	waitForScreen(t, router, screen, " stats ")
+
+	now := time.Now()
+	for i, cpu := range []float64{10, 40} {
+		events <- statusEvent{
+			Phase: phaseMain, Name: "api", Version: uint64(i + 1), Label: statusRunning, Running: true, PID: 7, StartedAt: now,
+			Usage: processUsage{CPUPercent: cpu, RSS: 8 << 20, Threads: 3, SampledAt: now.Add(time.Duration(i) * time.Second)},
+		}
+	}
+	waitForScreen(t, router, screen, "cpu   40.0% ▂▄")
+	waitForScreen(t, router, screen, "40.0% 8.0MiB 3t")
+
+	screen.InjectKey(tcell.KeyRune, 'c', tcell.ModNone)
+	waitForScreenWithout(t, router, screen, " stats ")
+}
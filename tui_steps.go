@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	// stepsName identifies the view listing the setup and shutdown commands.
+	stepsName = "steps"
+	// stepOutputLines bounds the output kept for each step.
+	stepOutputLines = 200
+	// stepSpinnerInterval is the delay between two spinner frames.
+	stepSpinnerInterval = 100 * time.Millisecond
+)
+
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+// tuiStep is a setup or shutdown command listed in the steps view.
+type tuiStep struct {
+	phase    commandPhase
+	name     string
+	status   statusEvent
+	output   ring[stepLine]
+	expanded bool
+}
+
+// stepLine is a line of output of a step.
+type stepLine struct {
+	stderr bool
+	text   string
+}
+
+// TrackSteps lists the setup and shutdown commands in the steps view.
+func (t *tuiRouter) TrackSteps(setup, shutdown []CommandConfig) {
+	t.stepMu.Lock()
+	defer t.stepMu.Unlock()
+	for _, phase := range []struct {
+		phase    commandPhase
+		commands []CommandConfig
+	}{{phaseSetup, setup}, {phaseShutdown, shutdown}} {
+		for _, c := range phase.commands {
+			t.steps = append(t.steps, &tuiStep{
+				phase:  phase.phase,
+				name:   c.Name,
+				status: statusEvent{Phase: phase.phase, Name: c.Name, Label: statusWaiting, State: recordPending},
+				output: ring[stepLine]{limit: stepOutputLines},
+			})
+		}
+	}
+	t.stepsDirty.Store(true)
+	t.dirty.Store(true)
+}
+
+// StepWriter keeps the output of a step for the steps view before passing it to next.
+func (t *tuiRouter) StepWriter(phase commandPhase, name, stream string, next func(string)) func(string) {
+	t.stepMu.Lock()
+	var step *tuiStep
+	for _, s := range t.steps {
+		if s.phase == phase && s.name == name {
+			step = s
+		}
+	}
+	t.stepMu.Unlock()
+	if step == nil {
+		return next
+	}
+	return func(line string) {
+		t.stepMu.Lock()
+		step.output.push(stepLine{stderr: stream == streamStderr, text: stripANSI(line)})
+		t.stepMu.Unlock()
+		t.stepsDirty.Store(true)
+		t.dirty.Store(true)
+		next(line)
+	}
+}
+
+// ShowSteps shows the steps view in place of the layout, or leaves it.
+func (t *tuiRouter) ShowSteps(show bool) {
+	t.queueUpdate(func() {
+		if (t.overlay == stepsName) != show {
+			t.toggleOverlay(stepsName)
+		}
+	})
+}
+
+// updateStep applies a status event of a setup or shutdown command. The
+// cursor follows the running step until the user moves it.
+func (t *tuiRouter) updateStep(ev statusEvent) {
+	t.stepMu.Lock()
+	defer t.stepMu.Unlock()
+	running := false
+	for i, step := range t.steps {
+		if step.phase == ev.Phase && step.name == ev.Name && step.status.Version <= ev.Version {
+			step.status = ev
+			if t.stepFollow && stepActive(ev) {
+				t.stepCursor = i
+			}
+		}
+		running = running || stepActive(step.status)
+	}
+	t.stepsRunning.Store(running)
+}
+
+// stepActive reports whether the step is running or about to.
+func stepActive(ev statusEvent) bool {
+	return ev.Running || ev.Label == statusStarting || ev.Label == statusRestarting
+}
+
+// handleStepKey moves the cursor of the steps view and expands the output of
+// the selected step. It reports whether the key was used.
+func (t *tuiRouter) handleStepKey(event *tcell.EventKey) bool {
+	t.stepMu.Lock()
+	defer func() {
+		t.stepMu.Unlock()
+		t.renderSteps(time.Now())
+	}()
+	if len(t.steps) == 0 {
+		return false
+	}
+	move := 0
+	switch {
+	case event.Key() == tcell.KeyUp || event.Key() == tcell.KeyRune && event.Rune() == 'k':
+		move = -1
+	case event.Key() == tcell.KeyDown || event.Key() == tcell.KeyRune && event.Rune() == 'j':
+		move = 1
+	case event.Key() == tcell.KeyEnter || event.Key() == tcell.KeyRune && event.Rune() == ' ':
+		step := t.steps[t.stepCursor]
+		step.expanded = !step.expanded
+		return true
+	default:
+		return false
+	}
+	t.stepFollow = false
+	t.stepCursor = min(max(t.stepCursor+move, 0), len(t.steps)-1)
+	return true
+}
+
+// renderSteps redraws the steps view. It must run on the UI goroutine.
+func (t *tuiRouter) renderSteps(now time.Time) {
+	view := t.views[stepsName]
+	if view == nil {
+		return
+	}
+	t.stepMu.Lock()
+	defer t.stepMu.Unlock()
+	var b strings.Builder
+	var phase commandPhase
+	for i, step := range t.steps {
+		if step.phase != phase {
+			phase = step.phase
+			fmt.Fprintf(&b, "[::b]%s[::-]\n", phase)
+		}
+		cursor := "  "
+		if i == t.stepCursor {
+			cursor = "[yellow]›[-] "
+		}
+		fmt.Fprintf(&b, "%s%s %s [%s]%s[-] [gray]%s[-]\n", cursor, stepIcon(step.status, now), tview.Escape(step.name),
+			statusColor(step.status), step.status.Label, stepDuration(step.status, now))
+		if !step.expanded {
+			continue
+		}
+		if step.output.dropped > 0 {
+			fmt.Fprintf(&b, "      [gray]… %d earlier lines[-]\n", step.output.dropped)
+		}
+		for _, line := range step.output.snapshot() {
+			lineColor := "gray"
+			if line.stderr {
+				lineColor = "red"
+			}
+			fmt.Fprintf(&b, "      [%s]%s[-]\n", lineColor, tview.Escape(line.text))
+		}
+	}
+	if len(t.steps) == 0 {
+		b.WriteString("[gray]no setup or shutdown commands[-]\n")
+	}
+	view.SetTitle(" " + stepsName + " [gray]↑↓ select  Enter output[-] ")
+	view.SetText(b.String())
+}
+
+// stepIcon renders a spinner for running steps and the outcome of finished ones.
+func stepIcon(ev statusEvent, now time.Time) string {
+	switch {
+	case stepActive(ev):
+		frame := spinnerFrames[now.UnixMilli()/stepSpinnerInterval.Milliseconds()%int64(len(spinnerFrames))]
+		return fmt.Sprintf("[yellow]%c[-]", frame)
+	case ev.State == recordCompleted:
+		return "[green]✔[-]"
+	case ev.State == recordTimedOut:
+		return "[fuchsia]⏱[-]"
+	case ev.State == recordPending || ev.State == recordAborted:
+		return "[gray]○[-]"
+	default:
+		return "[red]✖[-]"
+	}
+}
+
+// stepDuration renders the time a step has been running, over all its attempts.
+func stepDuration(ev statusEvent, now time.Time) string {
+	d := ev.Runtime
+	if ev.Running {
+		d += now.Sub(ev.StartedAt)
+	}
+	switch {
+	case d <= 0 && !ev.Running:
+		return ""
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	default:
+		return formatUptime(d)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestStepIconAndDuration(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name         string
+		ev           statusEvent
+		wantIcon     string
+		wantDuration string
+	}{
+		{name: "waiting", ev: statusEvent{State: recordPending, Label: statusWaiting}, wantIcon: "[gray]○[-]"},
+		{name: "completed", ev: statusEvent{State: recordCompleted, Label: "exited(0)", Runtime: 1500 * time.Millisecond}, wantIcon: "[green]✔[-]", wantDuration: "1s"},
+		{name: "fast", ev: statusEvent{State: recordCompleted, Label: "exited(0)", Runtime: 42 * time.Millisecond}, wantIcon: "[green]✔[-]", wantDuration: "42ms"},
+		{name: "failed", ev: statusEvent{State: recordFailed, Label: "exited(1)", Runtime: time.Second}, wantIcon: "[red]✖[-]", wantDuration: "1s"},
+		{name: "timed out", ev: statusEvent{State: recordTimedOut, Label: statusTimedOut, Runtime: time.Minute}, wantIcon: "[fuchsia]⏱[-]", wantDuration: "1m00s"},
+		{
+			name:         "running",
+			ev:           statusEvent{State: recordRunning, Label: statusRunning, Running: true, StartedAt: now.Add(-2 * time.Second), Runtime: time.Second},
+			wantIcon:     "[yellow]" + string(spinnerFrames[now.UnixMilli()/100%int64(len(spinnerFrames))]) + "[-]",
+			wantDuration: "3s",
+		},
+	}
+	for _, tt := range tests {
+		if got := stepIcon(tt.ev, now); got != tt.wantIcon {
+			t.Errorf("%s: stepIcon() = %q, want %q", tt.name, got, tt.wantIcon)
+		}
+		if got := stepDuration(tt.ev, now); got != tt.wantDuration {
+			t.Errorf("%s: stepDuration() = %q, want %q", tt.name, got, tt.wantDuration)
+		}
+	}
+}
+
+func TestTUIRouterSteps(t *testing.T) {
+	origErrorOutput := errorOutput
+	defer func() { errorOutput = origErrorOutput }()
+
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{})
+	errorOutput = router.BaseWriter()
+	setup := []CommandConfig{
+		{Name: "migrate", Cmd: "echo", Args: []string{"migrated 3 tables"}},
+		{Name: "seed", Cmd: "false"},
+	}
+	router.TrackSteps(setup, []CommandConfig{{Name: "cleanup", Cmd: "true"}})
+	summary := newRunSummary()
+	router.WatchStatus(summary.Subscribe())
+	router.ShowSteps(true)
+	waitForScreen(t, router, screen, "○ cleanup waiting")
+
+	if err := runSetupSequence(setup, []*color.Color{nil}, router, summary); err == nil {
+		t.Fatal("runSetupSequence() error = nil, want the failure of seed")
+	}
+	waitForScreen(t, router, screen, "✔ migrate exited(0)")
+	waitForScreen(t, router, screen, "› ✖ seed exited(1)")
+
+	screen.InjectKey(tcell.KeyUp, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForScreen(t, router, screen, "      migrated 3 tables")
+
+	screen.InjectKey(tcell.KeyRune, 'p', tcell.ModNone)
+	waitForScreen(t, router, screen, "[setup:migrate] migrated 3 tables")
+}
+
+func TestTUIRouterFinishKeepOpen(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{})
+	waitForScreen(t, router, screen, "api ● waiting")
+
+	done := make(chan struct{})
+	go func() {
+		router.Finish(true)
+		close(done)
+	}()
+	waitForScreen(t, router, screen, "press q or Enter to exit")
+	select {
+	case <-done:
+		t.Fatal("Finish(true) returned before a key was pressed")
+	default:
+	}
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Finish(true) did not return after q")
+	}
+}
+
+func TestTUIRouterStoppedBeforeFinish(t *testing.T) {
+	router, screen := newSimulatedTUIRouter(t, []string{"api"}, tuiOptions{})
+	waitForScreen(t, router, screen, "api ● waiting")
+	// A second interrupt stops the TUI before the shutdown commands run.
+	router.Stop()
+	<-router.runDone
+
+	done := make(chan struct{})
+	go func() {
+		router.ShowSteps(true)
+		router.Finish(true)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("ShowSteps and Finish hung once the TUI had stopped")
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultWatchDebounce = 200 * time.Millisecond
+
+// WatchConfig restarts a main command when the files it is built from change.
+type WatchConfig struct {
+	// Include and Exclude are globs relative to the working directory, where
+	// ** matches any number of directories and a pattern without a slash
+	// matches the file name at any depth.
+	Include  []string `yaml:"include"`
+	Exclude  []string `yaml:"exclude"`
+	Debounce string   `yaml:"debounce"`
+	// OnChange runs with sh -c before the restart, which only happens when it
+	// succeeds.
+	OnChange string `yaml:"onChange"`
+}
+
+// fileWatcher reports the paths changed under a set of directories.
+type fileWatcher interface {
+	Events() <-chan string
+	Close() error
+}
+
+// check reports the invalid globs and debounce of w.
+func (w *WatchConfig) check(name string) error {
+	if w == nil {
+		return nil
+	}
+	if _, err := time.ParseDuration(w.Debounce); w.Debounce != "" && err != nil {
+		return fmt.Errorf("invalid duration for watch.debounce in command '%s': %w", name, err)
+	}
+	for _, pattern := range append(append([]string(nil), w.Include...), w.Exclude...) {
+		if _, err := path.Match(filepath.ToSlash(pattern), ""); err != nil {
+			return fmt.Errorf("invalid glob '%s' in the watch of command '%s': %w", pattern, name, err)
+		}
+	}
+	return nil
+}
+
+// matchGlob reports whether name matches pattern. ** matches any number of
+// path segments and a pattern without a slash matches the last segment.
+func matchGlob(pattern, name string) bool {
+	pattern, name = filepath.ToSlash(pattern), filepath.ToSlash(name)
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(path.Clean(pattern), "/"), strings.Split(path.Clean(name), "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// watchRoots returns the directories to watch for the include globs: the
+// leading segments of each pattern without wildcards.
+func watchRoots(include []string) []string {
+	var roots []string
+	for _, pattern := range include {
+		root := "."
+		if pattern = filepath.ToSlash(pattern); strings.Contains(pattern, "/") {
+			segments := strings.Split(path.Clean(pattern), "/")
+			var fixed []string
+			for _, segment := range segments[:len(segments)-1] {
+				if strings.ContainsAny(segment, `*?[\`) {
+					break
+				}
+				fixed = append(fixed, segment)
+			}
+			switch {
+			case len(fixed) == 1 && fixed[0] == "":
+				root = "/"
+			case len(fixed) > 0:
+				root = strings.Join(fixed, "/")
+			}
+		}
+		if root = filepath.FromSlash(root); !slices.Contains(roots, root) {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+// commandWatcher turns the changes of the files matching a watch config into
+// restart requests for the worker of a command.
+type commandWatcher struct {
+	name     string
+	config   WatchConfig
+	debounce time.Duration
+	dir      string
+	control  chan<- controlRequest
+	// output receives the output lines of the onChange command.
+	output func(string)
+	ctx    context.Context
+}
+
+// watchFiles watches the files of c until the returned function is called.
+// Changes restart the command through its control channel, so that the
+// process is terminated like for any other restart request.
+func watchFiles(c CommandConfig, control chan<- controlRequest, output func(string)) func() {
+	if c.Watch == nil {
+		return func() {}
+	}
+	w := &commandWatcher{
+		name:     c.Name,
+		config:   *c.Watch,
+		debounce: mustParseDurationField("watch.debounce", c.Watch.Debounce, c.Name),
+		control:  control,
+		output:   output,
+	}
+	if w.debounce <= 0 {
+		w.debounce = defaultWatchDebounce
+	}
+	if len(w.config.Include) == 0 {
+		w.config.Include = []string{"**"}
+	}
+	w.dir, _ = os.Getwd() //nolint:errcheck
+	watcher, err := newFileWatcher(watchRoots(w.config.Include), w.skipDir)
+	if err != nil {
+		baseLog("[%s] file watching disabled: %v", c.Name, err)
+		return func() {}
+	}
+	var cancel context.CancelFunc
+	w.ctx, cancel = context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer recoverPanic(c.Name + " watch")
+		defer wg.Done()
+		w.loop(watcher.Events())
+	}()
+	return func() {
+		cancel()
+		_ = watcher.Close() //nolint:errcheck
+		wg.Wait()
+	}
+}
+
+// loop waits for the changes to settle for the debounce delay before acting
+// on them. Changes made while onChange runs are handled after it.
+func (w *commandWatcher) loop(events <-chan string) {
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	defer timer.Stop()
+	changed := ""
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case name, ok := <-events:
+			if !ok {
+				return
+			}
+			if !w.matches(name) {
+				continue
+			}
+			changed = name
+			timer.Reset(w.debounce)
+		case <-timer.C:
+			w.changed(changed)
+		}
+	}
+}
+
+func (w *commandWatcher) changed(name string) {
+	if w.config.OnChange != "" {
+		baseLog("[%s] %s changed, running onChange", w.name, name)
+		if err := w.runOnChange(name); err != nil {
+			baseLog("[%s] onChange failed, not restarting: %v", w.name, err)
+			return
+		}
+	}
+	// When a request is already pending, extra changes are coalesced.
+	sendControl(w.control, controlRequest{action: actionRestart, reason: fmt.Sprintf("%s changed", name)})
+}
+
+// runOnChange runs the onChange command, streaming its output to the panel
+// of the command.
+func (w *commandWatcher) runOnChange(name string) error {
+	cmd := exec.CommandContext(w.ctx, "sh", "-c", w.config.OnChange) // #nosec G204 -- command comes from the user's config
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("GONCURRENTLY_COMMAND=%s", w.name),
+		fmt.Sprintf("GONCURRENTLY_FILE=%s", name),
+	)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		if w.output != nil {
+			w.output(scanner.Text())
+		}
+	}
+	return cmd.Wait()
+}
+
+// matches reports whether a changed file is included and not excluded.
+func (w *commandWatcher) matches(name string) bool {
+	return w.matchAny(w.config.Include, name) && !w.matchAny(w.config.Exclude, name)
+}
+
+// skipDir reports whether a directory must not be watched: .git directories
+// and the excluded ones.
+func (w *commandWatcher) skipDir(dir string) bool {
+	return filepath.Base(dir) == ".git" || w.matchAny(w.config.Exclude, dir)
+}
+
+// matchAny matches name against patterns, converting it to an absolute or a
+// relative path like each pattern.
+func (w *commandWatcher) matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		candidate := name
+		switch {
+		case filepath.IsAbs(pattern) && !filepath.IsAbs(name):
+			candidate = filepath.Join(w.dir, name)
+		case !filepath.IsAbs(pattern) && filepath.IsAbs(name):
+			if rel, err := filepath.Rel(w.dir, name); err == nil {
+				candidate = rel
+			}
+		}
+		if matchGlob(pattern, candidate) {
+			return true
+		}
+	}
+	return false
+}
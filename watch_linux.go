@@ -0,0 +1,165 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const inotifyMask = unix.IN_CLOSE_WRITE | unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO
+
+// inotifyWatcher watches directory trees with inotify, which is not
+// recursive: every directory gets its own watch, including the ones created
+// later.
+type inotifyWatcher struct {
+	file   *os.File
+	fd     int
+	skip   func(string) bool
+	dirs   map[int32]string
+	events chan string
+	done   chan struct{}
+}
+
+// newFileWatcher watches the trees under roots, leaving out the directories
+// skip reports.
+func newFileWatcher(roots []string, skip func(string) bool) (fileWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify: %w", err)
+	}
+	w := &inotifyWatcher{
+		// A non-blocking descriptor is served by the runtime poller, so Close
+		// interrupts a pending read.
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		fd:     fd,
+		skip:   skip,
+		dirs:   make(map[int32]string),
+		events: make(chan string),
+		done:   make(chan struct{}),
+	}
+	for _, root := range roots {
+		if err := w.addTree(root, false); err != nil {
+			_ = w.file.Close() //nolint:errcheck
+			return nil, err
+		}
+	}
+	go w.read()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Events() <-chan string {
+	return w.events
+}
+
+func (w *inotifyWatcher) Close() error {
+	select {
+	case <-w.done:
+		return nil
+	default:
+		close(w.done)
+	}
+	return w.file.Close()
+}
+
+// addTree watches root and its subdirectories. For a directory created while
+// watching, the files already in it are reported as changes, since they were
+// written before its watch existed.
+func (w *inotifyWatcher) addTree(root string, created bool) error {
+	return filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if name == root && !created {
+				return err
+			}
+			return nil
+		}
+		if !entry.IsDir() {
+			if created {
+				w.send(name)
+			}
+			return nil
+		}
+		if name != root && w.skip(name) {
+			return filepath.SkipDir
+		}
+		wd, err := unix.InotifyAddWatch(w.fd, name, inotifyMask)
+		if err != nil {
+			if name == root && !created {
+				return fmt.Errorf("watch %s: %w", name, err)
+			}
+			return nil
+		}
+		w.dirs[int32(wd)] = name // #nosec G115 -- watch descriptors are small
+		return nil
+	})
+}
+
+// read decodes the inotify events until the watcher is closed.
+func (w *inotifyWatcher) read() {
+	defer recoverPanic("file watcher")
+	defer close(w.events)
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				baseLog("file watcher stopped: %v", err)
+			}
+			return
+		}
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset])) // #nosec G103 -- decoding the kernel's event layout
+			nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+			offset += unix.SizeofInotifyEvent + int(event.Len)
+			w.handle(event, string(trimNUL(nameBytes)))
+		}
+	}
+}
+
+func (w *inotifyWatcher) handle(event *unix.InotifyEvent, name string) {
+	switch {
+	case event.Mask&unix.IN_Q_OVERFLOW != 0:
+		// Events were lost: report a change of the first root.
+		for _, dir := range w.dirs {
+			w.send(dir)
+			return
+		}
+	case event.Mask&unix.IN_IGNORED != 0:
+		delete(w.dirs, event.Wd)
+		return
+	}
+	dir, ok := w.dirs[event.Wd]
+	if !ok || name == "" {
+		return
+	}
+	path := filepath.Join(dir, name)
+	if event.Mask&unix.IN_ISDIR != 0 {
+		if event.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 && !w.skip(path) {
+			_ = w.addTree(path, true) //nolint:errcheck
+		}
+		return
+	}
+	w.send(path)
+}
+
+func (w *inotifyWatcher) send(path string) {
+	select {
+	case w.events <- path:
+	case <-w.done:
+	}
+}
+
+func trimNUL(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[:i]
+		}
+	}
+	return b
+}
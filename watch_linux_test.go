@@ -0,0 +1,142 @@
+//go:build linux
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitForEvent(t *testing.T, events <-chan string, want string) {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case got := <-events:
+			if got == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no event for %s", want)
+		}
+	}
+}
+
+func TestInotifyWatcher(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "ignored"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := newFileWatcher([]string{dir}, func(name string) bool { return filepath.Base(name) == "ignored" })
+	if err != nil {
+		t.Fatalf("newFileWatcher() error = %v", err)
+	}
+	defer watcher.Close() //nolint:errcheck
+
+	write := func(name string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	waitForEvent(t, watcher.Events(), write("main.go"))
+
+	if err := os.MkdirAll(filepath.Join(dir, "pkg", "db"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvent(t, watcher.Events(), write(filepath.Join("pkg", "db", "db.go")))
+
+	write(filepath.Join("ignored", "cache.go"))
+	last := write("last.go")
+	select {
+	case got := <-watcher.Events():
+		if got != last {
+			t.Errorf("event for %s, want %s", got, last)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event after a skipped directory")
+	}
+
+	if err := watcher.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if _, ok := <-watcher.Events(); ok {
+		t.Error("events channel still open after Close")
+	}
+}
+
+func TestNewFileWatcherMissingRoot(t *testing.T) {
+	if _, err := newFileWatcher([]string{filepath.Join(t.TempDir(), "missing")}, func(string) bool { return false }); err == nil {
+		t.Error("expected an error for a missing root")
+	}
+}
+
+func TestWatchFiles(t *testing.T) {
+	origErrorOutput := errorOutput
+	errorOutput = io.Discard
+	defer func() { errorOutput = origErrorOutput }()
+
+	tests := []struct {
+		name     string
+		onChange string
+		restart  bool
+		output   string
+	}{
+		{"restart", "", true, ""},
+		{"onChange succeeds", `echo "built $GONCURRENTLY_COMMAND"`, true, "built api"},
+		{"onChange fails", "echo broken; exit 1", false, "broken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			control := make(chan controlRequest, 1)
+			var mu sync.Mutex
+			var lines []string
+			stop := watchFiles(CommandConfig{Name: "api", Watch: &WatchConfig{
+				Include:  []string{filepath.Join(dir, "**", "*.go")},
+				Debounce: "50ms",
+				OnChange: tt.onChange,
+			}}, control, func(line string) {
+				mu.Lock()
+				lines = append(lines, line)
+				mu.Unlock()
+			})
+			defer stop()
+
+			for _, name := range []string{"notes.txt", "a.go", "b.go"} {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			select {
+			case req := <-control:
+				if !tt.restart {
+					t.Fatalf("unexpected request %+v", req)
+				}
+				if req.action != actionRestart || !strings.HasSuffix(req.reason, "b.go changed") {
+					t.Errorf("request = %+v, want a restart for b.go", req)
+				}
+			case <-time.After(time.Second):
+				if tt.restart {
+					t.Fatal("no restart requested after a change")
+				}
+			}
+			select {
+			case req := <-control:
+				t.Errorf("changes were not debounced: %+v", req)
+			case <-time.After(100 * time.Millisecond):
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if got := strings.Join(lines, "\n"); got != tt.output {
+				t.Errorf("onChange output = %q, want %q", got, tt.output)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+var errWatchUnsupported = errors.New("file watching is only supported on Linux")
+
+func newFileWatcher([]string, func(string) bool) (fileWatcher, error) {
+	return nil, errWatchUnsupported
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/api/main.go", true},
+		{"*.go", "main_test.go.orig", false},
+		{"**", "web/src/app.ts", true},
+		{"src/**/*.ts", "src/app.ts", true},
+		{"src/**/*.ts", "src/routes/users/list.ts", true},
+		{"src/**/*.ts", "test/app.ts", false},
+		{"src/*.ts", "src/routes/list.ts", false},
+		{"./cmd/*/main.go", "cmd/api/main.go", true},
+		{"node_modules/**", "node_modules", true},
+		{"node_modules/**", "node_modules/react/index.js", true},
+		{"/srv/app/**/*.go", "/srv/app/internal/db.go", true},
+		{"/srv/app/**/*.go", "/srv/other/db.go", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWatchRoots(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		want    []string
+	}{
+		{"file names", []string{"*.go", "go.mod"}, []string{"."}},
+		{"directories", []string{"src/**/*.ts", "src/*.css", "cmd/api/main.go"}, []string{"src", "cmd/api"}},
+		{"wildcard first", []string{"*/main.go"}, []string{"."}},
+		{"absolute", []string{"/srv/app/**"}, []string{"/srv/app"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := watchRoots(tt.include); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("watchRoots(%v) = %v, want %v", tt.include, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchConfigCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		watch   *WatchConfig
+		wantErr string
+	}{
+		{"none", nil, ""},
+		{"valid", &WatchConfig{Include: []string{"**/*.go"}, Exclude: []string{"vendor/**"}, Debounce: "300ms"}, ""},
+		{"invalid debounce", &WatchConfig{Debounce: "soon"}, "watch.debounce"},
+		{"invalid include", &WatchConfig{Include: []string{"src/[a"}}, "src/[a"},
+		{"invalid exclude", &WatchConfig{Exclude: []string{"[z-"}}, "[z-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.watch.check("api")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("check() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("check() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommandWatcherMatches(t *testing.T) {
+	w := &commandWatcher{
+		dir: "/srv/app",
+		config: WatchConfig{
+			Include: []string{"**/*.go", "/srv/app/templates/*.html"},
+			Exclude: []string{"*_test.go", "vendor/**"},
+		},
+	}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"main.go", true},
+		{"/srv/app/internal/db.go", true},
+		{"internal/db_test.go", false},
+		{"vendor/github.com/x/y.go", false},
+		{"templates/index.html", true},
+		{"README.md", false},
+	}
+	for _, tt := range tests {
+		if got := w.matches(tt.name); got != tt.want {
+			t.Errorf("matches(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if !w.skipDir("vendor") || !w.skipDir("web/.git") || w.skipDir("internal") {
+		t.Error("unexpected skipped directories")
+	}
+}